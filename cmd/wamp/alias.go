@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/aziyan99/wamp/internal/cli"
+	"github.com/aziyan99/wamp/internal/site"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+func newSiteAliasCmd() *cli.Command {
+	aliasCmd := cli.NewCommand("alias", "Manages the extra domains of a site", "Serves a site under more domains than its name, e.g. one per tenant of an app routing on the host name. Every alias is added to the ServerAlias lines of the vhost, the certificate of HTTPS sites and the hosts file.", nil)
+
+	aliasAddCmd := cli.NewCommand("add", "Adds a domain to a site", "Appends the domain to the ServerAlias lines of the site vhost, reissues the certificate of HTTPS sites with the domain as an extra name and writes the domain into the hosts file. A name without a dot gets site.tld from wamp.ini. Wildcards such as *.shop.test are served but have to be added to the hosts file by hand. A running Apache is restarted once httpd -t accepts the change.", func(cmd *cli.Command, args []string) error {
+		return changeAlias(cmd, args[0], args[1], true)
+	})
+	aliasAddCmd.ArgsUsage = "<site-name> <domain>"
+	aliasAddCmd.Args = cli.ExactArgs(2)
+	aliasAddCmd.ValidArgsFunction = completeSites
+
+	aliasRmCmd := cli.NewCommand("rm", "Removes a domain from a site", "Drops the domain from the ServerAlias lines of the site vhost, reissues the certificate of HTTPS sites without it and removes it from the hosts file. A running Apache is restarted once httpd -t accepts the change.", func(cmd *cli.Command, args []string) error {
+		return changeAlias(cmd, args[0], args[1], false)
+	})
+	aliasRmCmd.Aliases = []string{"remove"}
+	aliasRmCmd.ArgsUsage = "<site-name> <domain>"
+	aliasRmCmd.Args = cli.ExactArgs(2)
+	aliasRmCmd.ValidArgsFunction = completeSiteAliases
+
+	aliasListCmd := cli.NewCommand("list", "Lists the extra domains of a site", "", func(cmd *cli.Command, args []string) error {
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		siteManager := site.New(registry, wwwDir, path.Join(apacheDir, layout.Config.Get("apache.active")), "", path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+		sitename, err := existingSiteName(siteManager, args[0])
+		if err != nil {
+			return err
+		}
+
+		s := registry.Get(sitename)
+		if s == nil {
+			return fmt.Errorf("site %s not found", sitename)
+		}
+
+		aliases := s.Aliases
+		if aliases == nil {
+			aliases = []string{}
+		}
+
+		if cmd.OutputFormat() == cli.OutputText {
+			if len(aliases) == 0 {
+				fmt.Printf("Site %s has no aliases, add one with 'wamp site alias add %s <domain>'.\n", s.Name, s.Name)
+			}
+
+			for _, alias := range aliases {
+				fmt.Println(alias)
+			}
+		}
+
+		cmd.SetResult(map[string]any{"site": s.Name, "aliases": aliases})
+
+		return nil
+	})
+	aliasListCmd.Aliases = []string{"ls"}
+	aliasListCmd.ArgsUsage = "<site-name>"
+	aliasListCmd.Args = cli.ExactArgs(1)
+	aliasListCmd.ValidArgsFunction = completeSites
+
+	aliasCmd.AddCommands(aliasAddCmd, aliasRmCmd, aliasListCmd)
+
+	return aliasCmd
+}
+
+// changeAlias adds alias to or removes it from the site called sitename and
+// restarts a running Apache, undoing the change when httpd -t rejects it.
+func changeAlias(cmd *cli.Command, name, alias string, add bool) error {
+	if err := loadConf(); err != nil {
+		return err
+	}
+
+	registry, err := site.LoadRegistry(layout.SitesPath())
+	if err != nil {
+		return err
+	}
+
+	siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), "", path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+	sitename, err := existingSiteName(siteManager, name)
+	if err != nil {
+		return err
+	}
+
+	alias, err = site.NormalizeAlias(alias, layout.Config.Get("site.tld"))
+	if err != nil {
+		return err
+	}
+
+	if warning := site.TLDWarning(alias); add && warning != "" {
+		util.PrintLog("WARN").Println(warning)
+	}
+
+	var updated *site.Site
+	if add {
+		updated, err = siteManager.AddAlias(sitename, alias)
+	} else {
+		updated, err = siteManager.RemoveAlias(sitename, alias)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to update site %s: %w", sitename, err)
+	}
+
+	restarted, err := restartApache(activeApache)
+	if err != nil {
+		if errors.Is(err, errConfigTest) {
+			var rollbackErr error
+			if add {
+				_, rollbackErr = siteManager.RemoveAlias(sitename, alias)
+			} else {
+				_, rollbackErr = siteManager.AddAlias(sitename, alias)
+			}
+			if rollbackErr != nil {
+				util.PrintLog("ERROR").Printf("Unable to restore the vhost of %s: %v\n", sitename, rollbackErr)
+			}
+		}
+		return err
+	}
+
+	if add {
+		util.PrintLog("INFO").Printf("Site '%s' answers to %s\n", sitename, alias)
+	} else {
+		util.PrintLog("INFO").Printf("Site '%s' no longer answers to %s\n", sitename, alias)
+	}
+	cmd.SetResult(map[string]any{"site": updated, "alias": alias, "restarted": restarted})
+
+	return nil
+}
+
+// completeSiteAliases completes the site, then its aliases.
+func completeSiteAliases(cmd *cli.Command, args []string, toComplete string) []string {
+	if len(args) != 1 {
+		return completeSites(cmd, args, toComplete)
+	}
+
+	registry, err := site.LoadRegistry(layout.SitesPath())
+	if err != nil {
+		return nil
+	}
+
+	siteManager := site.New(registry, wwwDir, path.Join(apacheDir, layout.Config.Get("apache.active")), "", path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+	sitename, err := existingSiteName(siteManager, args[0])
+	if err != nil {
+		return nil
+	}
+
+	if s := registry.Get(sitename); s != nil {
+		return s.Aliases
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/aziyan99/wamp/internal/apache"
+	"github.com/aziyan99/wamp/internal/cli"
+	"github.com/aziyan99/wamp/internal/manager"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+func newApacheCmd() *cli.Command {
+	apacheCmd := cli.NewCommand("apache", "Manages Apache", "", nil)
+	apacheStartCmd := cli.NewCommand("start", "Starts Apache", "Starts Apache. Uses the active version from wamp.ini unless a version is given. The HTTP port is set to apache.port first.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		apacheVersion, err := selectApacheVersion(args)
+		if err != nil {
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Use Apache: %s\n", apacheVersion)
+		util.PrintLog("INFO").Println("Apache starting...")
+
+		if err := startApache(apacheVersion); err != nil {
+			return fmt.Errorf("apache unable to start: %w", err)
+		}
+
+		util.PrintLog("INFO").Println("Apache started")
+		cmd.SetResult(serviceResult{Service: "apache", Version: apacheVersion, Status: "started"})
+
+		return nil
+	})
+	apacheStartCmd.ArgsUsage = "[version]"
+	apacheStartCmd.Args = cli.MaximumNArgs(1)
+	apacheStartCmd.ValidArgsFunction = completeApacheVersions
+
+	apacheStopCmd := cli.NewCommand("stop", "Stops Apache", "Stops Apache. Uses the active version from wamp.ini unless a version is given.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		apacheVersion, err := selectApacheVersion(args)
+		if err != nil {
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Use Apache: %s\n", apacheVersion)
+		util.PrintLog("INFO").Println("Apache stopping...")
+
+		if err := apacheProcess(apacheVersion).Stop(); err != nil {
+			return fmt.Errorf("apache unable to stop: %w", err)
+		}
+
+		util.PrintLog("INFO").Println("Apache stopped")
+		cmd.SetResult(serviceResult{Service: "apache", Version: apacheVersion, Status: "stopped"})
+
+		return nil
+	})
+	apacheStopCmd.ArgsUsage = "[version]"
+	apacheStopCmd.Args = cli.MaximumNArgs(1)
+	apacheStopCmd.ValidArgsFunction = completeApacheVersions
+	apacheCmd.AddCommands(apacheStartCmd, apacheStopCmd)
+
+	return apacheCmd
+}
+
+// selectApacheVersion returns the version given on the command line, falling
+// back to the active one from wamp.ini.
+func selectApacheVersion(args []string) (string, error) {
+	if len(args) == 0 {
+		return activeApache, nil
+	}
+
+	installed, err := util.DirExists(path.Join(apacheDir, args[0]))
+	if err != nil {
+		return "", err
+	}
+
+	if !installed {
+		return "", fmt.Errorf("Apache %s is not installed", args[0])
+	}
+
+	return args[0], nil
+}
+
+func apacheProcess(version string) *manager.Manager {
+	return manager.New(
+		version,
+		path.Join(apacheDir, version, "bin")+"\\httpd.exe",
+		tmpDir,
+	)
+}
+
+// errConfigTest wraps a failed httpd -t in restartApache, in which case the
+// running Apache was left alone.
+var errConfigTest = errors.New("apache config test failed")
+
+// restartApache gracefully restarts Apache version when it is running so it
+// picks up changed vhosts, see apache.Reload. apache.port is applied and the
+// configuration is checked with httpd -t first; a failing check leaves the
+// running Apache untouched. It reports whether Apache was restarted.
+func restartApache(version string) (bool, error) {
+	if !apacheProcess(version).Running() {
+		return false, nil
+	}
+
+	a2Root := path.Join(apacheDir, version)
+	if err := apache.SetPort(a2Root, layout.Config.Get("apache.port")); err != nil {
+		return false, err
+	}
+
+	if err := apache.ConfigTest(a2Root); err != nil {
+		return false, fmt.Errorf("%w: %v", errConfigTest, err)
+	}
+
+	util.PrintLog("INFO").Printf("Restarting Apache %s...\n", version)
+	if err := apache.Reload(a2Root); err != nil {
+		return false, fmt.Errorf("apache unable to restart: %w", err)
+	}
+
+	return true, nil
+}
+
+// startApache applies apache.port to the configuration of version and starts it.
+func startApache(version string) error {
+	if err := apache.SetPort(path.Join(apacheDir, version), layout.Config.Get("apache.port")); err != nil {
+		return err
+	}
+
+	return apacheProcess(version).Start()
+}
+
+// withApacheStopped runs fn with Apache version stopped when it is running,
+// since Windows can not move or rename the site logs Apache keeps open, and
+// starts it again afterwards, also when fn failed.
+func withApacheStopped(version string, fn func() error) error {
+	running := apacheProcess(version).Running()
+	if running {
+		util.PrintLog("INFO").Printf("Stopping Apache %s...\n", version)
+		if err := apacheProcess(version).Stop(); err != nil {
+			return fmt.Errorf("apache unable to stop: %w", err)
+		}
+	}
+
+	err := fn()
+
+	if running {
+		util.PrintLog("INFO").Printf("Starting Apache %s...\n", version)
+		if startErr := startApache(version); startErr != nil && err == nil {
+			err = fmt.Errorf("apache unable to start: %w", startErr)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/aziyan99/wamp/internal/cli"
+	"github.com/aziyan99/wamp/internal/site"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// installedDirs lists the directory names under dir, e.g. the installed
+// versions under bin/php. Errors are ignored since completion is best effort.
+func installedDirs(dir string) []string {
+	names, _ := util.SubDirs(dir)
+	return names
+}
+
+func completePHPVersions(cmd *cli.Command, args []string, toComplete string) []string {
+	return installedDirs(phpDir)
+}
+
+func completeApacheVersions(cmd *cli.Command, args []string, toComplete string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+
+	return installedDirs(apacheDir)
+}
+
+func completeMysqlVersions(cmd *cli.Command, args []string, toComplete string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+
+	return installedDirs(mysqlDir)
+}
+
+func completeSites(cmd *cli.Command, args []string, toComplete string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+
+	registry, err := site.LoadRegistry(layout.SitesPath())
+	if err != nil {
+		return nil
+	}
+
+	return registry.Names()
+}
+
+// completeSitePHP completes the site, then the PHP version of site php.
+func completeSitePHP(cmd *cli.Command, args []string, toComplete string) []string {
+	if len(args) == 1 {
+		return installedDirs(phpDir)
+	}
+
+	return completeSites(cmd, args, toComplete)
+}
+
+func completeTrashedSites(cmd *cli.Command, args []string, toComplete string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+
+	siteManager := site.New(nil, wwwDir, "", "", "", tmpDir, layout.TrashDir())
+	entries, err := siteManager.TrashedSites()
+	if err != nil {
+		return nil
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if !seen[entry.Site.Name] {
+			seen[entry.Site.Name] = true
+			names = append(names, entry.Site.Name)
+		}
+	}
+
+	return names
+}
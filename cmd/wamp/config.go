@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/cli"
+	"github.com/aziyan99/wamp/internal/util"
+	"github.com/aziyan99/wamp/internal/wamp"
+)
+
+// configValue is the JSON result describing one wamp.ini setting.
+type configValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Source      string `json:"source"`
+	Origin      string `json:"origin,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// configExplanation is the JSON result of config explain.
+type configExplanation struct {
+	configValue
+	Layers []wamp.Layer `json:"layers"`
+}
+
+// openConf loads wamp.ini for editing. A missing file yields an empty
+// document so settings can be written before install.
+func openConf() (*util.INI, error) {
+	conf, err := util.LoadConf(layout.ConfPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return util.NewINI(), nil
+		}
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// settingValue resolves s through every layer, reading wamp.ini from conf.
+func settingValue(conf *util.INI, s wamp.Setting) configValue {
+	resolved := layout.Config.WithConf(conf).Resolve(s)
+
+	return configValue{
+		Key:         s.Name(),
+		Value:       resolved.Value,
+		Source:      resolved.Source,
+		Origin:      resolved.Origin,
+		Default:     s.Default,
+		Type:        s.Type.String(),
+		Description: s.Description,
+	}
+}
+
+// sourceNote describes where a value came from for the text output.
+func sourceNote(v configValue) string {
+	switch v.Source {
+	case wamp.SourceDefault:
+		return " (default)"
+	case wamp.SourceEnv, wamp.SourceFlag:
+		return " (from " + v.Origin + ")"
+	}
+
+	if v.Type == "" {
+		return " (unknown)"
+	}
+
+	return ""
+}
+
+func newConfigCmd() *cli.Command {
+	configCmd := cli.NewCommand("config", "Manages wamp.ini settings", "Reads and writes the settings in wamp.ini. Keys are written as section.key, e.g. apache.active.", nil)
+
+	configListCmd := cli.NewCommand("list", "Lists all settings", "Lists every known setting with its effective value and where it comes from. Keys in wamp.ini that are not known settings are listed too.", func(cmd *cli.Command, args []string) error {
+		conf, err := openConf()
+		if err != nil {
+			return err
+		}
+
+		values := []configValue{}
+		known := map[string]bool{}
+		for _, s := range wamp.Settings {
+			known[s.Name()] = true
+			values = append(values, settingValue(conf, s))
+		}
+
+		for _, section := range conf.Sections() {
+			if strings.HasPrefix(section, wamp.ProfilePrefix) {
+				continue // see profile list
+			}
+
+			for _, key := range conf.Keys(section) {
+				name := section + "." + key
+				if known[name] {
+					continue
+				}
+				value, _ := conf.GetConf(section, key)
+				values = append(values, configValue{Key: name, Value: value, Source: wamp.SourceFile, Origin: layout.ConfPath, Description: "not a known setting"})
+			}
+		}
+
+		if cmd.OutputFormat() == cli.OutputText {
+			longest := 0
+			for _, v := range values {
+				if len(v.Key) > longest {
+					longest = len(v.Key)
+				}
+			}
+
+			for _, v := range values {
+				fmt.Printf("%s%s = %s%s\n", v.Key, strings.Repeat(" ", longest-len(v.Key)), v.Value, sourceNote(v))
+			}
+		}
+
+		cmd.SetResult(values)
+
+		return nil
+	})
+	configListCmd.Aliases = []string{"ls"}
+	configListCmd.Args = cli.NoArgs
+
+	configGetCmd := cli.NewCommand("get", "Prints a setting", "Prints the effective value of a setting: the --set flag, then the WAMP_<SECTION>_<KEY> environment variable, then wamp.ini, then the default.", func(cmd *cli.Command, args []string) error {
+		s, err := wamp.LookupSetting(args[0])
+		if err != nil {
+			return err
+		}
+
+		conf, err := openConf()
+		if err != nil {
+			return err
+		}
+
+		value := settingValue(conf, s)
+		if cmd.OutputFormat() == cli.OutputText {
+			fmt.Println(value.Value)
+		}
+		cmd.SetResult(value)
+
+		return nil
+	})
+	configGetCmd.ArgsUsage = "<key>"
+	configGetCmd.Args = cli.ExactArgs(1)
+	configGetCmd.ValidArgsFunction = completeConfigKeys
+
+	configSetCmd := cli.NewCommand("set", "Changes a setting", "Validates and writes a setting to wamp.ini. Version settings only accept versions installed under bin.", func(cmd *cli.Command, args []string) error {
+		s, err := wamp.LookupSetting(args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := s.Validate(layout, args[1]); err != nil {
+			return err
+		}
+
+		conf, err := openConf()
+		if err != nil {
+			return err
+		}
+
+		conf.SetConf(s.Section, s.Key, args[1])
+		if err := conf.SaveConf(layout.ConfPath); err != nil {
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("%s set to %s\n", s.Name(), args[1])
+
+		value := settingValue(conf, s)
+		if value.Source != wamp.SourceFile {
+			util.PrintLog("WARN").Printf("%s is still overridden by %s\n", s.Name(), value.Origin)
+		}
+		cmd.SetResult(value)
+
+		return nil
+	})
+	configSetCmd.ArgsUsage = "<key> <value>"
+	configSetCmd.Args = cli.ExactArgs(2)
+	configSetCmd.ValidArgsFunction = func(cmd *cli.Command, args []string, toComplete string) []string {
+		switch len(args) {
+		case 0:
+			return wamp.SettingNames()
+		case 1:
+			s, err := wamp.LookupSetting(args[0])
+			if err != nil || s.Allowed == nil {
+				return nil
+			}
+			allowed, _ := s.Allowed(layout)
+			return allowed
+		default:
+			return nil
+		}
+	}
+
+	configUnsetCmd := cli.NewCommand("unset", "Removes a setting", "Removes a setting from wamp.ini so its default applies again.", func(cmd *cli.Command, args []string) error {
+		s, err := wamp.LookupSetting(args[0])
+		if err != nil {
+			return err
+		}
+
+		conf, err := openConf()
+		if err != nil {
+			return err
+		}
+
+		if conf.DeleteConf(s.Section, s.Key) {
+			if err := conf.SaveConf(layout.ConfPath); err != nil {
+				return err
+			}
+			util.PrintLog("INFO").Printf("%s unset\n", s.Name())
+		} else {
+			util.PrintLog("INFO").Printf("%s is not set\n", s.Name())
+		}
+
+		cmd.SetResult(settingValue(conf, s))
+
+		return nil
+	})
+	configUnsetCmd.ArgsUsage = "<key>"
+	configUnsetCmd.Args = cli.ExactArgs(1)
+	configUnsetCmd.ValidArgsFunction = completeConfigKeys
+
+	configExplainCmd := cli.NewCommand("explain", "Shows where a setting comes from", "Shows the value every layer has for a setting, lowest precedence first, and which one is in effect.", func(cmd *cli.Command, args []string) error {
+		s, err := wamp.LookupSetting(args[0])
+		if err != nil {
+			return err
+		}
+
+		resolved := layout.Config.Resolve(s)
+
+		if cmd.OutputFormat() == cli.OutputText {
+			fmt.Printf("%s = %s\n", s.Name(), resolved.Value)
+			for _, layer := range resolved.Layers {
+				value := layer.Value
+				if !layer.Set {
+					value = "(not set)"
+				}
+
+				marker := ""
+				if layer.Set && layer.Source == resolved.Source {
+					marker = " <- effective"
+				}
+
+				origin := ""
+				if layer.Origin != "" {
+					origin = " [" + layer.Origin + "]"
+				}
+
+				fmt.Printf("  %-8s  %s%s%s\n", layer.Source, value, origin, marker)
+			}
+		}
+
+		cmd.SetResult(configExplanation{
+			configValue: configValue{
+				Key:         s.Name(),
+				Value:       resolved.Value,
+				Source:      resolved.Source,
+				Origin:      resolved.Origin,
+				Default:     s.Default,
+				Type:        s.Type.String(),
+				Description: s.Description,
+			},
+			Layers: resolved.Layers,
+		})
+
+		return nil
+	})
+	configExplainCmd.ArgsUsage = "<key>"
+	configExplainCmd.Args = cli.ExactArgs(1)
+	configExplainCmd.ValidArgsFunction = completeConfigKeys
+
+	configCmd.AddCommands(configListCmd, configGetCmd, configSetCmd, configUnsetCmd, configExplainCmd)
+
+	return configCmd
+}
+
+func completeConfigKeys(cmd *cli.Command, args []string, toComplete string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+
+	return wamp.SettingNames()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/aziyan99/wamp/internal/cli"
+	"github.com/aziyan99/wamp/internal/util"
+	"github.com/aziyan99/wamp/internal/wamp"
+)
+
+func newMigrateCmd() *cli.Command {
+	migrateCmd := cli.NewCommand("migrate", "Upgrades wamp.ini and the stack layout", "Runs the migrations between the schema_version in wamp.ini and the one this wamp understands, after backing up wamp.ini and sites.json. Other commands never migrate; they warn while migrations are pending.", func(cmd *cli.Command, args []string) error {
+		dryRun := cmd.GetBool("dry-run")
+
+		result, err := wamp.Migrate(layout, dryRun)
+		if err != nil {
+			return err
+		}
+
+		if len(result.Applied) == 0 {
+			util.PrintLog("INFO").Printf("wamp.ini is up to date (schema version %d)\n", result.To)
+		} else if dryRun {
+			for _, description := range result.Applied {
+				util.PrintLog("INFO").Printf("Pending: %s\n", description)
+			}
+		} else {
+			util.PrintLog("INFO").Printf("Migrated wamp.ini from schema version %d to %d\n", result.From, result.To)
+		}
+
+		cmd.SetResult(result)
+
+		return nil
+	})
+	migrateCmd.Args = cli.NoArgs
+	migrateCmd.AddBoolFlag("dry-run", "n", false, "List the pending migrations without running them")
+
+	return migrateCmd
+}
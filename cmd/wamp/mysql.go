@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/aziyan99/wamp/internal/cli"
+	"github.com/aziyan99/wamp/internal/manager"
+	"github.com/aziyan99/wamp/internal/mysql"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+func newMysqlCmd() *cli.Command {
+	mysqlCmd := cli.NewCommand("mysql", "Manages MySQL", "", nil)
+	mysqlStartCmd := cli.NewCommand("start", "Starts MySQL", "Starts MySQL. Uses the active version from wamp.ini unless a version is given. The port is set to mysql.port first.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		mysqlVersion, err := selectMysqlVersion(args)
+		if err != nil {
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Use MySQL: %s\n", mysqlVersion)
+
+		util.PrintLog("INFO").Println("MySQL starting...")
+
+		if err := startMysql(mysqlVersion); err != nil {
+			return fmt.Errorf("MySQL unable to start: %w", err)
+		}
+
+		util.PrintLog("INFO").Println("MySQL started.")
+		cmd.SetResult(serviceResult{Service: "mysql", Version: mysqlVersion, Status: "started"})
+
+		return nil
+	})
+	mysqlStartCmd.ArgsUsage = "[version]"
+	mysqlStartCmd.Args = cli.MaximumNArgs(1)
+	mysqlStartCmd.ValidArgsFunction = completeMysqlVersions
+
+	mysqlStopCmd := cli.NewCommand("stop", "Stops MySQL", "Stops MySQL. Uses the active version from wamp.ini unless a version is given.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		mysqlVersion, err := selectMysqlVersion(args)
+		if err != nil {
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Use MySQL: %s\n", mysqlVersion)
+
+		util.PrintLog("INFO").Println("MySQL stopping...")
+
+		if err := mysqlProcess(mysqlVersion).Stop(); err != nil {
+			return fmt.Errorf("MySQL unable to stop: %w", err)
+		}
+
+		util.PrintLog("INFO").Println("MySQL stopped.")
+		cmd.SetResult(serviceResult{Service: "mysql", Version: mysqlVersion, Status: "stopped"})
+
+		return nil
+	})
+	mysqlStopCmd.ArgsUsage = "[version]"
+	mysqlStopCmd.Args = cli.MaximumNArgs(1)
+	mysqlStopCmd.ValidArgsFunction = completeMysqlVersions
+	mysqlCmd.AddCommands(mysqlStartCmd, mysqlStopCmd)
+
+	return mysqlCmd
+}
+
+// selectMysqlVersion returns the version given on the command line, falling
+// back to the active one from wamp.ini.
+func selectMysqlVersion(args []string) (string, error) {
+	if len(args) == 0 {
+		return activeMysql, nil
+	}
+
+	installed, err := util.DirExists(path.Join(mysqlDir, args[0]))
+	if err != nil {
+		return "", err
+	}
+
+	if !installed {
+		return "", fmt.Errorf("MySQL %s is not installed", args[0])
+	}
+
+	return args[0], nil
+}
+
+func mysqlProcess(version string) *manager.Manager {
+	return manager.New(
+		version,
+		path.Join(mysqlDir, version, "bin", "mysqld.exe"),
+		tmpDir,
+		"--console",
+	)
+}
+
+// startMysql applies mysql.port to the my.ini of version and starts it.
+func startMysql(version string) error {
+	if err := mysql.SetPort(path.Join(mysqlDir, version), layout.Config.Get("mysql.port")); err != nil {
+		return err
+	}
+
+	return mysqlProcess(version).Start()
+}
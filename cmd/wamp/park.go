@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/aziyan99/wamp/internal/cli"
+	"github.com/aziyan99/wamp/internal/site"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// parkListItem is one entry of the park list JSON result.
+type parkListItem struct {
+	*site.Park
+	Sites []string `json:"sites"`
+}
+
+func newParkCmd() *cli.Command {
+	parkCmd := cli.NewCommand("park", "Serves every subfolder of a directory as a site", "Parks a directory: every folder directly inside it is served as <folder>.<tld>, with tld from site.tld in wamp.ini. The vhosts are generated into sites-enabled of the active Apache and the hosts entries are added. Run 'wamp park sync' after adding or removing folders.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		dir, err := site.ParkDir(args[0])
+		if err != nil {
+			return err
+		}
+
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		park := &site.Park{Dir: dir, PHP: cmd.GetString("php"), SSL: cmd.GetBool("ssl")}
+		if existing := registry.GetPark(dir); existing != nil {
+			util.PrintLog("INFO").Printf("%s is already parked, updating it\n", dir)
+		}
+
+		registry.PutPark(park)
+		if err := registry.Save(); err != nil {
+			return err
+		}
+
+		result, err := syncPark(registry, park)
+		if err != nil {
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Parked %s: %d site(s) added\n", dir, len(result.Added))
+		cmd.SetResult(result)
+
+		return nil
+	})
+	parkCmd.ArgsUsage = "<dir>"
+	parkCmd.Args = cli.ExactArgs(1)
+	parkCmd.AddFlag("php", "p", "", "The php version of the sites (default: php.default from wamp.ini)")
+	parkCmd.AddBoolFlag("ssl", "s", false, "Whether to use SSL for the sites")
+	parkCmd.RegisterFlagCompletion("php", completePHPVersions)
+
+	parkSyncCmd := cli.NewCommand("sync", "Syncs the sites of the parked directories", "Adds a site for every new folder of the parked directories and removes the sites of folders that are gone. The folders themselves are never deleted.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		parks := registry.Parks()
+		if len(args) > 0 {
+			dir, err := site.ParkDir(args[0])
+			if err != nil {
+				return err
+			}
+
+			park := registry.GetPark(dir)
+			if park == nil {
+				return fmt.Errorf("%s is not parked", dir)
+			}
+			parks = []*site.Park{park}
+		}
+
+		results := []*site.SyncResult{}
+		for _, park := range parks {
+			result, err := syncPark(registry, park)
+			if err != nil {
+				return err
+			}
+
+			util.PrintLog("INFO").Printf("Synced %s: %d added, %d removed\n", park.Dir, len(result.Added), len(result.Removed))
+			results = append(results, result)
+		}
+
+		cmd.SetResult(results)
+
+		return nil
+	})
+	parkSyncCmd.ArgsUsage = "[dir]"
+	parkSyncCmd.Args = cli.MaximumNArgs(1)
+	parkSyncCmd.ValidArgsFunction = completeParks
+
+	parkListCmd := cli.NewCommand("list", "Lists the parked directories", "", func(cmd *cli.Command, args []string) error {
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		items := []parkListItem{}
+		for _, park := range registry.Parks() {
+			item := parkListItem{Park: park, Sites: []string{}}
+			for _, s := range registry.List() {
+				if s.Parked == park.Dir {
+					item.Sites = append(item.Sites, s.Name)
+				}
+			}
+			items = append(items, item)
+		}
+
+		if cmd.OutputFormat() == cli.OutputText {
+			if len(items) == 0 {
+				fmt.Println("No parked directories, park one with 'wamp park <dir>'.")
+			}
+
+			for _, item := range items {
+				fmt.Printf("%s  (%d sites)\n", item.Dir, len(item.Sites))
+			}
+		}
+
+		cmd.SetResult(items)
+
+		return nil
+	})
+	parkListCmd.Aliases = []string{"ls"}
+	parkListCmd.Args = cli.NoArgs
+
+	parkRmCmd := cli.NewCommand("rm", "Stops serving a parked directory", "Removes the sites generated for the directory and forgets it. The folders are kept.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		dir, err := site.ParkDir(args[0])
+		if err != nil {
+			return err
+		}
+
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		park := registry.GetPark(dir)
+		if park == nil {
+			return fmt.Errorf("%s is not parked", dir)
+		}
+
+		siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), "", path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+		removed, err := siteManager.Unpark(park)
+		if err != nil {
+			return err
+		}
+
+		registry.DeletePark(dir)
+		if err := registry.Save(); err != nil {
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Unparked %s: %d site(s) removed\n", dir, len(removed))
+		cmd.SetResult(map[string]any{"dir": dir, "removed": removed})
+
+		return nil
+	})
+	parkRmCmd.Aliases = []string{"remove"}
+	parkRmCmd.ArgsUsage = "<dir>"
+	parkRmCmd.Args = cli.ExactArgs(1)
+	parkRmCmd.ValidArgsFunction = completeParks
+
+	parkCmd.AddCommands(parkSyncCmd, parkListCmd, parkRmCmd)
+
+	return parkCmd
+}
+
+// syncPark syncs the sites of park with the active Apache.
+func syncPark(registry *site.Registry, park *site.Park) (*site.SyncResult, error) {
+	selectedPHPPath, err := resolvePHP(park.PHP)
+	if err != nil {
+		return nil, err
+	}
+
+	siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), selectedPHPPath, path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+	return siteManager.SyncPark(park, selectedPHPPath, layout.Config.Get("site.tld"))
+}
+
+func completeParks(cmd *cli.Command, args []string, toComplete string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+
+	registry, err := site.LoadRegistry(layout.SitesPath())
+	if err != nil {
+		return nil
+	}
+
+	dirs := []string{}
+	for _, park := range registry.Parks() {
+		dirs = append(dirs, park.Dir)
+	}
+
+	return dirs
+}
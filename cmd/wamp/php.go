@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/aziyan99/wamp/internal/cli"
+	"github.com/aziyan99/wamp/internal/php"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+func newPHPCmd() *cli.Command {
+	//php-8.4.9-nts-Win32-vs17-x64
+	phpCmd := cli.NewCommand("php", "Manages PHP", "Manage PHP instances", nil)
+	phpInstallCmd := cli.NewCommand("install", "Install specific PHP version", "", func(cmd *cli.Command, args []string) error {
+		phpManager := php.New(phpDir, tmpDir)
+		if err := phpManager.Install(cmd.Context(), args[0]); err != nil {
+			return fmt.Errorf("failed to download php %s: %w", args[0], err)
+		}
+
+		util.PrintLog("INFO").Printf("PHP %s installed", args[0])
+		cmd.SetResult(componentResult{Version: args[0], Path: path.Join(phpDir, args[0])})
+
+		return nil
+	})
+	phpInstallCmd.ArgsUsage = "<full-version-name>"
+	phpInstallCmd.Args = cli.ExactArgs(1)
+	phpCmd.AddCommands(phpInstallCmd)
+
+	return phpCmd
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path"
+
+	"github.com/aziyan99/wamp/internal/cli"
+)
+
+// pluginHandler runs "wamp <name>" as the external executable "wamp-<name>"
+// found in bin/etc, the plugins directory or PATH.
+func pluginHandler(app *cli.Command) *cli.PluginHandler {
+	return &cli.PluginHandler{
+		Prefix: "wamp-",
+		Dirs: func() []string {
+			// The global flags given before the plugin name are parsed by now.
+			if err := resolveLayout(app.GetString("wamp-dir"), app.GetString("config"), app.GetStringSlice("set")); err != nil {
+				return nil
+			}
+
+			return []string{path.Join(binDir, "etc"), path.Join(wampDir, "plugins")}
+		},
+		Env: pluginEnv,
+	}
+}
+
+// pluginEnv describes the stack to plugins. The active versions are left
+// out when wamp.ini is missing, e.g. before "wamp install".
+func pluginEnv() []string {
+	env := []string{
+		"WAMP_DIR=" + wampDir,
+		"WAMP_CONFIG=" + layout.ConfPath,
+		"WAMP_BIN_DIR=" + binDir,
+		"WAMP_PHP_DIR=" + phpDir,
+		"WAMP_WWW_DIR=" + wwwDir,
+		"WAMP_TMP_DIR=" + tmpDir,
+	}
+
+	if err := loadConf(); err == nil {
+		env = append(env,
+			"WAMP_APACHE="+activeApache,
+			"WAMP_APACHE_DIR="+path.Join(apacheDir, activeApache),
+			"WAMP_MYSQL="+activeMysql,
+			"WAMP_MYSQL_DIR="+path.Join(mysqlDir, activeMysql),
+		)
+	}
+
+	return env
+}
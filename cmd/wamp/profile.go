@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/cli"
+	"github.com/aziyan99/wamp/internal/php"
+	"github.com/aziyan99/wamp/internal/site"
+	"github.com/aziyan99/wamp/internal/util"
+	"github.com/aziyan99/wamp/internal/wamp"
+)
+
+// profileResult is the JSON result of profile use.
+type profileResult struct {
+	Profile     string   `json:"profile"`
+	Apache      string   `json:"apache"`
+	MySQL       string   `json:"mysql"`
+	PHP         string   `json:"php"`
+	CopiedSites []string `json:"copied_sites"`
+	Restarted   []string `json:"restarted"`
+}
+
+// profileListItem is one entry of the profile list JSON result.
+type profileListItem struct {
+	wamp.Profile
+	Active bool `json:"active"`
+}
+
+func newProfileCmd() *cli.Command {
+	profileCmd := cli.NewCommand("profile", "Manages stack profiles", "Switches between Apache/MySQL/PHP combinations defined as [profile.<name>] sections in wamp.ini.", nil)
+
+	profileListCmd := cli.NewCommand("list", "Lists the profiles", "", func(cmd *cli.Command, args []string) error {
+		conf, err := openConf()
+		if err != nil {
+			return err
+		}
+
+		active := layout.Config.WithConf(conf).Get("profile.active")
+		items := []profileListItem{}
+		for _, p := range wamp.Profiles(conf) {
+			items = append(items, profileListItem{Profile: p, Active: p.Name == active})
+		}
+
+		if cmd.OutputFormat() == cli.OutputText {
+			if len(items) == 0 {
+				fmt.Println("No profiles, add a [profile.<name>] section to wamp.ini.")
+			}
+
+			for _, item := range items {
+				marker := " "
+				if item.Active {
+					marker = "*"
+				}
+
+				names := make([]string, 0, len(item.Settings))
+				for name := range item.Settings {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+
+				values := []string{}
+				for _, name := range names {
+					values = append(values, name+"="+item.Settings[name])
+				}
+
+				fmt.Printf("%s %s  %s\n", marker, item.Name, strings.Join(values, " "))
+			}
+		}
+
+		cmd.SetResult(items)
+
+		return nil
+	})
+	profileListCmd.Aliases = []string{"ls"}
+	profileListCmd.Args = cli.NoArgs
+
+	profileUseCmd := cli.NewCommand("use", "Switches to a profile", "Stops the running Apache and MySQL, makes the versions, default PHP and ports of the profile active in wamp.ini, then starts again what was running. Sites are copied to the new Apache with their pinned PHP versions.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		conf, err := openConf()
+		if err != nil {
+			return err
+		}
+
+		profile, err := wamp.LookupProfile(conf, args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := profile.Validate(layout); err != nil {
+			return err
+		}
+
+		if keyword, ok := profile.Settings["php.default"]; ok {
+			if _, err := php.Search(phpDir, keyword); err != nil {
+				return fmt.Errorf("profile %s: %w", profile.Name, err)
+			}
+		}
+
+		oldApache, oldMysql := activeApache, activeMysql
+		apacheRunning := apacheProcess(oldApache).Running()
+		mysqlRunning := mysqlProcess(oldMysql).Running()
+
+		// restartOld brings back what was stopped when the switch fails
+		apacheStopped, mysqlStopped := false, false
+		restartOld := func() {
+			if apacheStopped {
+				if err := startApache(oldApache); err != nil {
+					util.PrintLog("ERROR").Printf("Unable to restart Apache %s: %v\n", oldApache, err)
+				}
+			}
+			if mysqlStopped {
+				if err := startMysql(oldMysql); err != nil {
+					util.PrintLog("ERROR").Printf("Unable to restart MySQL %s: %v\n", oldMysql, err)
+				}
+			}
+		}
+
+		if apacheRunning {
+			util.PrintLog("INFO").Printf("Stopping Apache %s...\n", oldApache)
+			if err := apacheProcess(oldApache).Stop(); err != nil {
+				return fmt.Errorf("apache unable to stop: %w", err)
+			}
+			apacheStopped = true
+		}
+
+		if mysqlRunning {
+			util.PrintLog("INFO").Printf("Stopping MySQL %s...\n", oldMysql)
+			if err := mysqlProcess(oldMysql).Stop(); err != nil {
+				restartOld()
+				return fmt.Errorf("MySQL unable to stop: %w", err)
+			}
+			mysqlStopped = true
+		}
+
+		result := profileResult{Profile: profile.Name, CopiedSites: []string{}, Restarted: []string{}}
+
+		if newApache, ok := profile.Settings["apache.active"]; ok && newApache != oldApache {
+			copied, err := site.CopySites(path.Join(apacheDir, oldApache), path.Join(apacheDir, newApache))
+			if err != nil {
+				restartOld()
+				return fmt.Errorf("unable to copy sites to Apache %s: %w", newApache, err)
+			}
+
+			for _, name := range copied {
+				util.PrintLog("INFO").Printf("Site '%s' copied to Apache %s\n", name, newApache)
+			}
+			result.CopiedSites = copied
+
+			registry, err := site.LoadRegistry(layout.SitesPath())
+			if err != nil {
+				restartOld()
+				return err
+			}
+
+			registry.MoveApache(path.Join(apacheDir, oldApache), path.Join(apacheDir, newApache))
+			if err := registry.Save(); err != nil {
+				restartOld()
+				return err
+			}
+		}
+
+		profile.Apply(conf)
+		if err := conf.SaveConf(layout.ConfPath); err != nil {
+			restartOld()
+			return err
+		}
+
+		layout.Config = layout.Config.WithConf(conf)
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		for _, name := range []string{"apache.active", "mysql.active", "php.default", "apache.port", "mysql.port"} {
+			s, _ := wamp.LookupSetting(name)
+			if resolved := layout.Config.Resolve(s); resolved.Source == wamp.SourceEnv || resolved.Source == wamp.SourceFlag {
+				util.PrintLog("WARN").Printf("%s is overridden by %s\n", name, resolved.Origin)
+			}
+		}
+
+		result.Apache = activeApache
+		result.MySQL = activeMysql
+		result.PHP = layout.Config.Get("php.default")
+
+		if apacheRunning {
+			util.PrintLog("INFO").Printf("Starting Apache %s...\n", activeApache)
+			if err := startApache(activeApache); err != nil {
+				return fmt.Errorf("apache unable to start: %w", err)
+			}
+			result.Restarted = append(result.Restarted, "apache")
+		}
+
+		if mysqlRunning {
+			util.PrintLog("INFO").Printf("Starting MySQL %s...\n", activeMysql)
+			if err := startMysql(activeMysql); err != nil {
+				return fmt.Errorf("MySQL unable to start: %w", err)
+			}
+			result.Restarted = append(result.Restarted, "mysql")
+		}
+
+		util.PrintLog("INFO").Printf("Profile '%s' active\n", profile.Name)
+		cmd.SetResult(result)
+
+		return nil
+	})
+	profileUseCmd.ArgsUsage = "<name>"
+	profileUseCmd.Args = cli.ExactArgs(1)
+	profileUseCmd.ValidArgsFunction = completeProfiles
+
+	profileCmd.AddCommands(profileListCmd, profileUseCmd)
+
+	return profileCmd
+}
+
+func completeProfiles(cmd *cli.Command, args []string, toComplete string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+
+	conf, err := openConf()
+	if err != nil {
+		return nil
+	}
+
+	names := []string{}
+	for _, p := range wamp.Profiles(conf) {
+		names = append(names, p.Name)
+	}
+
+	return names
+}
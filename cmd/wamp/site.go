@@ -0,0 +1,587 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aziyan99/wamp/internal/apache"
+	"github.com/aziyan99/wamp/internal/cli"
+	"github.com/aziyan99/wamp/internal/php"
+	"github.com/aziyan99/wamp/internal/site"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+func newSiteCmd() *cli.Command {
+	siteCmd := cli.NewCommand("site", "Manages sites", "", nil)
+	siteAddCmd := cli.NewCommand("add", "Adds a site", "Creates www/<site-name> with its vhost and hosts entry. With --type the site is scaffolded as a laravel, wordpress, moodle or static project: the PHP extensions it needs are enabled, an empty site directory is bootstrapped (composer create-project, or an archive cached in tmp/cache) and the docroot and .htaccess follow the project conventions.", func(cmd *cli.Command, args []string) error {
+
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Use Apache: %s\n", activeApache)
+		util.PrintLog("INFO").Printf("Use MySQL: %s\n", activeMysql)
+
+		util.PrintLog("INFO").Println("Creating site...")
+		sslEnable := cmd.GetBool("ssl")
+
+		selectedPHPPath, err := selectedPHP(cmd)
+		if err != nil {
+			return err
+		}
+
+		sitename, err := siteName(args[0])
+		if err != nil {
+			return err
+		}
+
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), selectedPHPPath, path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+		createdSite, err := siteManager.Add(cmd.Context(), sitename, cmd.GetString("type"), sslEnable)
+		if err != nil {
+			return fmt.Errorf("unable to create site %s: %w", sitename, err)
+		}
+
+		util.PrintLog("INFO").Printf("Site '%s' created.\n", sitename)
+		cmd.SetResult(createdSite)
+
+		return nil
+	})
+	siteAddCmd.ArgsUsage = "<site-name>"
+	siteAddCmd.Args = cli.ExactArgs(1)
+	siteAddCmd.AddFlag("php", "p", "", "The php version (default: php.default from wamp.ini)")
+	siteAddCmd.AddBoolFlag("ssl", "s", false, "Whether to use SSL")
+	siteAddCmd.AddFlag("type", "t", "", "The project type: "+strings.Join(site.ProjectTypeNames(), ", "))
+	siteAddCmd.RegisterFlagCompletion("php", completePHPVersions)
+	siteAddCmd.RegisterFlagCompletion("type", func(cmd *cli.Command, args []string, toComplete string) []string {
+		return site.ProjectTypeNames()
+	})
+
+	siteLinkCmd := cli.NewCommand("link", "Serves an existing directory as a site", "Creates the vhost, certificate and hosts entry for a directory anywhere on disk, e.g. a repository in D:\\code. Its public folder is used as docroot when it holds an index.php or index.html. site rm never deletes a linked directory.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Use Apache: %s\n", activeApache)
+
+		selectedPHPPath, err := selectedPHP(cmd)
+		if err != nil {
+			return err
+		}
+
+		sitename, err := siteName(args[0])
+		if err != nil {
+			return err
+		}
+
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), selectedPHPPath, path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+		linkedSite, err := siteManager.Link(sitename, args[1], cmd.GetBool("ssl"))
+		if err != nil {
+			return fmt.Errorf("unable to link site %s: %w", sitename, err)
+		}
+
+		util.PrintLog("INFO").Printf("Site '%s' linked to %s.\n", sitename, linkedSite.DocRoot)
+		cmd.SetResult(linkedSite)
+
+		return nil
+	})
+	siteLinkCmd.ArgsUsage = "<site-name> <path>"
+	siteLinkCmd.Args = cli.ExactArgs(2)
+	siteLinkCmd.AddFlag("php", "p", "", "The php version (default: php.default from wamp.ini)")
+	siteLinkCmd.AddBoolFlag("ssl", "s", false, "Whether to use SSL")
+	siteLinkCmd.RegisterFlagCompletion("php", completePHPVersions)
+
+	siteRmCmd := cli.NewCommand("rm", "Removes a site", "Detaches the site: its vhost, certificate and logs are moved into the trash and its hosts entry is removed. The site files are kept unless --purge is given, which moves them into the trash as well. A running Apache is stopped while the logs are moved and started again afterwards. 'wamp site restore' brings the site back.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Use Apache: %s\n", activeApache)
+		util.PrintLog("INFO").Printf("Use MySQL: %s\n", activeMysql)
+		util.PrintLog("INFO").Println("Removing site...")
+
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), "", path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+		sitename, err := existingSiteName(siteManager, args[0])
+		if err != nil {
+			return err
+		}
+
+		// Apache keeps the site logs open, so it is stopped while they are
+		// moved into the trash
+		var entry *site.Trashed
+		err = withApacheStopped(activeApache, func() error {
+			var err error
+			entry, err = siteManager.Remove(sitename, cmd.GetBool("purge"))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("unable to remove site %s: %w", sitename, err)
+		}
+
+		if !entry.Purged {
+			util.PrintLog("INFO").Printf("Site files kept in %s\n", entry.Site.Dir)
+		}
+		util.PrintLog("INFO").Printf("site: '%s' removed, restore it with 'wamp site restore %s'.\n", sitename, sitename)
+		cmd.SetResult(map[string]any{"name": sitename, "status": "removed", "purged": entry.Purged, "trash": entry.Dir})
+
+		return nil
+	})
+	siteRmCmd.Aliases = []string{"remove", "delete"}
+	siteRmCmd.ArgsUsage = "<site-name>"
+	siteRmCmd.Args = cli.ExactArgs(1)
+	siteRmCmd.AddBoolFlag("purge", "", false, "Also move the site files into the trash")
+	siteRmCmd.ValidArgsFunction = completeSites
+
+	siteRestoreCmd := cli.NewCommand("restore", "Restores a removed site", "Brings back the most recently removed site with that name from the trash: its vhost, certificate, logs, hosts entry and, if it was purged, its files. The site is attached to the active Apache, which is restarted once httpd -t accepts the vhost; otherwise the site goes back into the trash.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), "", path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+		sitename, err := existingSiteName(siteManager, args[0])
+		if err != nil {
+			return err
+		}
+
+		entries, err := siteManager.TrashedSites()
+		if err != nil {
+			return err
+		}
+
+		purged := false
+		for _, entry := range entries {
+			if entry.Site.Name == sitename {
+				purged = entry.Purged
+				break
+			}
+		}
+
+		restored, err := siteManager.Restore(sitename)
+		if err != nil {
+			return fmt.Errorf("unable to restore site %s: %w", sitename, err)
+		}
+
+		restarted, err := restartApache(activeApache)
+		if err != nil {
+			if errors.Is(err, errConfigTest) {
+				if _, rollbackErr := siteManager.Remove(sitename, purged); rollbackErr != nil {
+					util.PrintLog("ERROR").Printf("Unable to move %s back into the trash: %v\n", sitename, rollbackErr)
+				}
+			}
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Site '%s' restored.\n", sitename)
+		cmd.SetResult(map[string]any{"site": restored, "restarted": restarted})
+
+		return nil
+	})
+	siteRestoreCmd.ArgsUsage = "<site-name>"
+	siteRestoreCmd.Args = cli.ExactArgs(1)
+	siteRestoreCmd.ValidArgsFunction = completeTrashedSites
+
+	siteTrashCmd := cli.NewCommand("trash", "Lists the removed sites", "Lists the removed sites 'wamp site restore' can bring back, most recent first. --empty deletes them for good, including the files of purged sites.", func(cmd *cli.Command, args []string) error {
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		siteManager := site.New(registry, wwwDir, "", "", path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+		if cmd.GetBool("empty") {
+			deleted, err := siteManager.EmptyTrash()
+			if err != nil {
+				return fmt.Errorf("unable to empty the trash: %w", err)
+			}
+
+			util.PrintLog("INFO").Printf("Deleted %d removed site(s) from the trash.\n", len(deleted))
+			cmd.SetResult(map[string]any{"deleted": deleted})
+
+			return nil
+		}
+
+		entries, err := siteManager.TrashedSites()
+		if err != nil {
+			return err
+		}
+
+		if cmd.OutputFormat() == cli.OutputText {
+			if len(entries) == 0 {
+				fmt.Println("The trash is empty.")
+			}
+
+			for _, entry := range entries {
+				files := "files kept in " + entry.Site.Dir
+				if entry.Purged {
+					files = "files purged"
+				}
+				fmt.Printf("%s  removed %s, %s\n", entry.Site.Name, entry.Removed.Local().Format("2006-01-02 15:04"), files)
+			}
+		}
+
+		cmd.SetResult(entries)
+
+		return nil
+	})
+	siteTrashCmd.Args = cli.NoArgs
+	siteTrashCmd.AddBoolFlag("empty", "", false, "Delete the removed sites for good")
+
+	siteListCmd := cli.NewCommand("list", "Lists the sites", "Lists the registered sites with their docroot, PHP version, SSL certificate expiry, hosts entry and the state of their vhost. The optional pattern filters the names, e.g. 'shop*'.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		pattern := "*"
+		if len(args) > 0 {
+			pattern = args[0]
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), "", path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+		statuses := []site.Status{}
+		for _, s := range registry.List() {
+			if matched, _ := path.Match(pattern, s.Name); !matched {
+				continue
+			}
+
+			if cmd.Changed("php") && !strings.Contains(s.PHPVersion, cmd.GetString("php")) {
+				continue
+			}
+
+			if cmd.Changed("ssl") && s.SSL != cmd.GetBool("ssl") {
+				continue
+			}
+
+			status := siteManager.Status(s)
+			if cmd.GetBool("unhealthy") && status.Healthy() {
+				continue
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		if cmd.OutputFormat() == cli.OutputText {
+			printSiteStatuses(statuses, len(registry.Names()))
+		}
+
+		cmd.SetResult(statuses)
+
+		return nil
+	})
+	siteListCmd.Aliases = []string{"ls"}
+	siteListCmd.ArgsUsage = "[pattern]"
+	siteListCmd.Args = cli.MaximumNArgs(1)
+	siteListCmd.AddFlag("php", "p", "", "Only sites whose PHP version contains this value")
+	siteListCmd.AddBoolFlag("ssl", "s", false, "Only sites with (--ssl) or without (--ssl=false) SSL")
+	siteListCmd.AddBoolFlag("unhealthy", "u", false, "Only sites with problems")
+	siteListCmd.RegisterFlagCompletion("php", completePHPVersions)
+
+	sitePHPCmd := cli.NewCommand("php", "Switches the PHP version of a site", "Points the vhost of the site at another installed PHP version, matched like --php of site add. Only the PHP lines of the vhost are rewritten and the site files are left alone. A running Apache is restarted after its configuration passed httpd -t; when the check fails the vhost is restored.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		selectedPHPPath, err := resolvePHP(args[1])
+		if err != nil {
+			return err
+		}
+
+		siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), selectedPHPPath, path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+		sitename, err := existingSiteName(siteManager, args[0])
+		if err != nil {
+			return err
+		}
+
+		old, err := siteManager.SetPHP(sitename, selectedPHPPath)
+		if err != nil {
+			return fmt.Errorf("unable to switch PHP of site %s: %w", sitename, err)
+		}
+
+		restarted, err := restartApache(activeApache)
+		if err != nil {
+			if errors.Is(err, errConfigTest) {
+				if _, rollbackErr := siteManager.SetPHP(sitename, old.PHPDir); rollbackErr != nil {
+					util.PrintLog("ERROR").Printf("Unable to restore the vhost of %s: %v\n", sitename, rollbackErr)
+				}
+			}
+			return err
+		}
+
+		util.PrintLog("INFO").Printf("Site '%s' switched from %s to %s.\n", sitename, old.PHPVersion, path.Base(selectedPHPPath))
+		cmd.SetResult(map[string]any{"site": registry.Get(sitename), "previous_php_version": old.PHPVersion, "restarted": restarted})
+
+		return nil
+	})
+	sitePHPCmd.ArgsUsage = "<site-name> <version>"
+	sitePHPCmd.Args = cli.ExactArgs(2)
+	sitePHPCmd.ValidArgsFunction = completeSitePHP
+
+	siteSecureCmd := cli.NewCommand("secure", "Serves a site over HTTPS", "Issues a certificate for the site in conf/sites-ssl and regenerates its vhost with the *:443 block. With --redirect, HTTP requests are redirected to HTTPS. The site files and hosts entry are left alone. A running Apache is restarted once httpd -t accepts the change.", func(cmd *cli.Command, args []string) error {
+		return toggleSSL(cmd, args[0], true)
+	})
+	siteSecureCmd.ArgsUsage = "<site-name>"
+	siteSecureCmd.Args = cli.ExactArgs(1)
+	siteSecureCmd.AddBoolFlag("redirect", "r", false, "Redirect HTTP to HTTPS")
+	siteSecureCmd.ValidArgsFunction = completeSites
+
+	siteUnsecureCmd := cli.NewCommand("unsecure", "Serves a site over HTTP only", "Regenerates the vhost of the site without the *:443 block and deletes its certificate. The site files and hosts entry are left alone. A running Apache is restarted once httpd -t accepts the change.", func(cmd *cli.Command, args []string) error {
+		return toggleSSL(cmd, args[0], false)
+	})
+	siteUnsecureCmd.ArgsUsage = "<site-name>"
+	siteUnsecureCmd.Args = cli.ExactArgs(1)
+	siteUnsecureCmd.ValidArgsFunction = completeSites
+
+	siteRenameCmd := cli.NewCommand("rename", "Renames a site", "Gives the site a new domain: its directory is moved when it is www/<old>, the DOMAIN and ROOT of its vhost are rewritten, its certificate is reissued, its logs are renamed and its hosts entry is swapped. Every step is rolled back when one fails. A running Apache is stopped during the rename and started again once httpd -t accepts the new vhost.", func(cmd *cli.Command, args []string) error {
+		if err := loadConf(); err != nil {
+			return err
+		}
+
+		registry, err := site.LoadRegistry(layout.SitesPath())
+		if err != nil {
+			return err
+		}
+
+		siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), "", path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+		oldName, err := existingSiteName(siteManager, args[0])
+		if err != nil {
+			return err
+		}
+
+		newName, err := siteName(args[1])
+		if err != nil {
+			return err
+		}
+
+		// Apache keeps the site logs open, so it is stopped during the rename
+		apacheRunning := apacheProcess(activeApache).Running()
+		var renamed *site.Site
+		err = withApacheStopped(activeApache, func() error {
+			var err error
+			renamed, err = siteManager.Rename(oldName, newName)
+			if err != nil || !apacheRunning {
+				return err
+			}
+
+			if testErr := apache.ConfigTest(path.Join(apacheDir, activeApache)); testErr != nil {
+				if _, rollbackErr := siteManager.Rename(newName, oldName); rollbackErr != nil {
+					util.PrintLog("ERROR").Printf("Unable to rename %s back to %s: %v\n", newName, oldName, rollbackErr)
+				}
+				return fmt.Errorf("%w: %v", errConfigTest, testErr)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("unable to rename site %s: %w", oldName, err)
+		}
+
+		util.PrintLog("INFO").Printf("Site '%s' renamed to '%s'.\n", oldName, newName)
+		cmd.SetResult(map[string]any{"previous_name": oldName, "site": renamed})
+
+		return nil
+	})
+	siteRenameCmd.ArgsUsage = "<old-name> <new-name>"
+	siteRenameCmd.Args = cli.ExactArgs(2)
+	siteRenameCmd.ValidArgsFunction = completeSites
+
+	siteCmd.AddCommands(siteAddCmd, siteLinkCmd, siteRmCmd, siteRestoreCmd, siteTrashCmd, siteListCmd, sitePHPCmd, siteSecureCmd, siteUnsecureCmd, siteRenameCmd, newSiteAliasCmd())
+
+	return siteCmd
+}
+
+// printSiteStatuses prints the site list as a table followed by the
+// problems found. registered is the number of sites before filtering.
+func printSiteStatuses(statuses []site.Status, registered int) {
+	if registered == 0 {
+		fmt.Println("No sites, add one with 'wamp site add <site-name>'.")
+		return
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("No matching sites.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DOMAIN\tDOCROOT\tPHP\tSSL\tCERT EXPIRES\tHOSTS\tVHOST")
+
+	for _, s := range statuses {
+		ssl, expires := "no", "-"
+		if s.SSL {
+			ssl = "yes"
+		}
+		if s.CertExpires != nil {
+			expires = s.CertExpires.Local().Format("2006-01-02")
+		}
+
+		hosts := "?"
+		if s.Hosts != nil {
+			hosts = "no"
+			if *s.Hosts {
+				hosts = "yes"
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.DocRoot, s.PHPVersion, ssl, expires, hosts, s.VHost)
+	}
+	w.Flush()
+
+	for _, s := range statuses {
+		for _, problem := range s.Problems {
+			fmt.Printf("%s: %s\n", s.Name, problem)
+		}
+	}
+}
+
+// siteName normalizes a site name given on the command line, appending
+// site.tld when it has no dot, and warns about TLDs unfit for local sites.
+func siteName(name string) (string, error) {
+	sitename, err := site.NormalizeName(name, layout.Config.Get("site.tld"))
+	if err != nil {
+		return "", err
+	}
+
+	if sitename != name {
+		util.PrintLog("INFO").Printf("Site name: %s\n", sitename)
+	}
+
+	if warning := site.TLDWarning(sitename); warning != "" {
+		util.PrintLog("WARN").Println(warning)
+	}
+
+	return sitename, nil
+}
+
+// existingSiteName returns the name an existing site is stored under, see
+// Manager.ExistingName.
+func existingSiteName(siteManager *site.Manager, name string) (string, error) {
+	return siteManager.ExistingName(name, layout.Config.Get("site.tld"))
+}
+
+// selectedPHP returns the dir of the PHP version matching --php, or
+// php.default from wamp.ini when the flag is not given.
+func selectedPHP(cmd *cli.Command) (string, error) {
+	return resolvePHP(cmd.GetString("php"))
+}
+
+// resolvePHP returns the dir of the installed PHP version matching keyword,
+// or php.default from wamp.ini when keyword is empty.
+func resolvePHP(keyword string) (string, error) {
+	if keyword == "" {
+		keyword = layout.Config.Get("php.default")
+	}
+
+	phpVersion, err := php.Search(phpDir, keyword)
+	if err != nil {
+		return "", fmt.Errorf("unable to get php: %w", err)
+	}
+
+	util.PrintLog("INFO").Printf("Use PHP: %s\n", phpVersion)
+
+	return path.Join(phpDir, phpVersion), nil
+}
+
+// toggleSSL runs site secure (secure) or site unsecure for sitename and
+// restarts Apache, restoring the previous SSL state when httpd -t fails.
+func toggleSSL(cmd *cli.Command, name string, secure bool) error {
+	if err := loadConf(); err != nil {
+		return err
+	}
+
+	registry, err := site.LoadRegistry(layout.SitesPath())
+	if err != nil {
+		return err
+	}
+
+	siteManager := site.New(registry, wwwDir, path.Join(apacheDir, activeApache), "", path.Join(binDir, "etc"), tmpDir, layout.TrashDir())
+
+	sitename, err := existingSiteName(siteManager, name)
+	if err != nil {
+		return err
+	}
+
+	previous := registry.Get(sitename)
+	if previous == nil {
+		return fmt.Errorf("site %s not found", sitename)
+	}
+	wasSSL, wasRedirect := previous.SSL, previous.Redirect
+
+	var updated *site.Site
+	if secure {
+		updated, err = siteManager.Secure(sitename, cmd.GetBool("redirect"))
+	} else {
+		updated, err = siteManager.Unsecure(sitename)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to update site %s: %w", sitename, err)
+	}
+
+	restarted, err := restartApache(activeApache)
+	if err != nil {
+		if errors.Is(err, errConfigTest) {
+			var rollbackErr error
+			if wasSSL {
+				_, rollbackErr = siteManager.Secure(sitename, wasRedirect)
+			} else {
+				_, rollbackErr = siteManager.Unsecure(sitename)
+			}
+			if rollbackErr != nil {
+				util.PrintLog("ERROR").Printf("Unable to restore the vhost of %s: %v\n", sitename, rollbackErr)
+			}
+		}
+		return err
+	}
+
+	scheme := "http"
+	if updated.SSL {
+		scheme = "https"
+	}
+
+	util.PrintLog("INFO").Printf("Site '%s' served on %s://%s\n", sitename, scheme, sitename)
+	cmd.SetResult(map[string]any{"site": updated, "restarted": restarted})
+
+	return nil
+}
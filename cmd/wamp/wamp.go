@@ -1,21 +1,22 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
-	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/aziyan99/wamp/internal/cli"
-	"github.com/aziyan99/wamp/internal/manager"
-	"github.com/aziyan99/wamp/internal/php"
-	"github.com/aziyan99/wamp/internal/site"
 	"github.com/aziyan99/wamp/internal/util"
 	"github.com/aziyan99/wamp/internal/wamp"
 )
 
+var layout wamp.Layout
 var wampDir string
 var binDir string
 var apacheDir string
@@ -27,245 +28,248 @@ var tmpDir string
 var activeApache string
 var activeMysql string
 
-func loadConf() error {
-	conf, err := util.LoadConf(path.Join(wampDir, "wamp.ini"))
-	if err != nil {
-		return err
-	}
-
-	var found bool
+// componentResult is the JSON result describing an installed component.
+type componentResult struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
 
-	activeApache, found = conf.GetConf("apache", "active")
-	if !found {
-		return errors.New("apache conf unavailable")
-	}
+// serviceResult is the JSON result of the start and stop commands.
+type serviceResult struct {
+	Service string `json:"service"`
+	Version string `json:"version"`
+	Status  string `json:"status"`
+}
 
-	activeMysql, found = conf.GetConf("mysql", "active")
-	if !found {
-		return errors.New("mySQL conf unavailable")
+// defaultWampDir returns WAMP_HOME when set, otherwise the directory of the executable.
+func defaultWampDir() (string, error) {
+	if home := os.Getenv("WAMP_HOME"); home != "" {
+		return home, nil
 	}
 
-	return nil
-}
-
-func main() {
-	var err error
-	wampDir, err = os.Executable()
+	exe, err := os.Executable()
 	if err != nil {
-		util.PrintLog("ERROR").Panic(err)
+		return "", err
 	}
 
-	wampDir = filepath.Dir(wampDir)
-
-	util.PrintLog("INFO").Printf("Wamp dir: %s\n", wampDir)
-
-	binDir = path.Join(wampDir, "bin")
-	apacheDir = path.Join(binDir, "apache")
-	mysqlDir = path.Join(binDir, "mysql")
-	phpDir = path.Join(binDir, "php")
-	wwwDir = path.Join(wampDir, "www")
-	tmpDir = path.Join(wampDir, "tmp")
-
-	app := cli.NewCommand(
-		os.Args[0],
-		"Wamp CLI",
-		"Yet another Wamp stack manager",
-		func(cmd *cli.Command, args []string) {
-			fmt.Println("Use 'help' to see available commands.")
-		},
-	)
-
-	wampManager := wamp.New(wampDir)
-	initCmd := cli.NewCommand("init", "Initializes the application", "", func(cmd *cli.Command, args []string) {
-		if err = wampManager.Init(); err != nil {
-			util.PrintLog("ERROR").Fatalf("%v\n", err)
-		}
-	})
-
-	installCmd := cli.NewCommand("install", "Installs the application", "", func(cmd *cli.Command, args []string) {
-		if err = wampManager.Install(); err != nil {
-			util.PrintLog("ERROR").Fatalf("%v\n", err)
-		}
-	})
-
-	uninstallCmd := cli.NewCommand("uninstall", "Uninstall WAMP", "", func(cmd *cli.Command, args []string) {
-		if err = wampManager.Clean(); err != nil {
-			util.PrintLog("ERROR").Fatalf("%v\n", err)
-		}
-	})
-	app.AddCommands(initCmd, installCmd, uninstallCmd)
+	return filepath.Dir(exe), nil
+}
 
-	apacheCmd := cli.NewCommand("apache", "Manages Apache", "", nil)
-	apacheStartCmd := cli.NewCommand("start", "Starts Apache", "", func(cmd *cli.Command, args []string) {
-		if err = loadConf(); err != nil {
-			util.PrintLog("ERROR").Fatalf("%v\n", err)
+// parseOverrides turns the "section.key=value" values of --set into
+// overrides for the config resolver.
+func parseOverrides(values []string) (map[string]string, error) {
+	overrides := map[string]string{}
+	for _, value := range values {
+		name, v, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --set %q, expected section.key=value", value)
 		}
 
-		apacheProcess := manager.New(
-			activeApache,
-			path.Join(apacheDir, activeApache, "bin")+"\\httpd.exe",
-			tmpDir,
-		)
-
-		util.PrintLog("INFO").Printf("Use Apache: %s\n", activeApache)
-		util.PrintLog("INFO").Println("Apache starting...")
-
-		err = apacheProcess.Start()
+		s, err := wamp.LookupSetting(strings.TrimSpace(name))
 		if err != nil {
-			util.PrintLog("ERROR").Fatalf("Apache unable to start. Error: %v\n", err)
+			return nil, err
 		}
 
-		util.PrintLog("INFO").Println("Apache started")
-	})
-
-	apacheStopCmd := cli.NewCommand("stop", "Stops Apache", "", func(cmd *cli.Command, args []string) {
-		if err = loadConf(); err != nil {
-			util.PrintLog("ERROR").Fatalf("%v\n", err)
+		if err := s.CheckType(v); err != nil {
+			return nil, err
 		}
 
-		apacheProcess := manager.New(
-			activeApache,
-			path.Join(apacheDir, activeApache, "bin")+"\\httpd.exe",
-			tmpDir,
-		)
+		overrides[s.Name()] = v
+	}
 
-		util.PrintLog("INFO").Printf("Use Apache: %s\n", activeApache)
-		util.PrintLog("INFO").Println("Apache stopping...")
+	return overrides, nil
+}
 
-		err = apacheProcess.Stop()
-		if err != nil {
-			util.PrintLog("ERROR").Fatalf("Apache unable to stop. Error: %v\n", err)
+// resolveLayout sets the stack locations. The root is taken from
+// wampDirOverride (--wamp-dir), WAMP_HOME or the executable directory, in
+// that order; confOverride (--config, WAMP_CONFIG) selects another wamp.ini.
+// overrides are the --set values.
+func resolveLayout(wampDirOverride, confOverride string, overrides []string) error {
+	dir := wampDirOverride
+	if dir == "" {
+		var err error
+		if dir, err = defaultWampDir(); err != nil {
+			return err
 		}
+	}
 
-		util.PrintLog("INFO").Println("Apache stopped")
-	})
-	apacheCmd.AddCommands(apacheStartCmd, apacheStopCmd)
-
-	mysqlCmd := cli.NewCommand("mysql", "Manages MySQL", "", nil)
-	mysqlStartCmd := cli.NewCommand("start", "Starts MySQL", "", func(cmd *cli.Command, args []string) {
-		if err = loadConf(); err != nil {
-			util.PrintLog("ERROR").Fatalf("%v\n", err)
-		}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
 
-		util.PrintLog("INFO").Printf("Use MySQL: %s\n", activeMysql)
+	if confOverride == "" {
+		confOverride = os.Getenv("WAMP_CONFIG")
+	}
 
-		mysqlProcess := manager.New(
-			activeMysql,
-			path.Join(mysqlDir, activeMysql, "bin", "mysqld.exe"),
-			tmpDir,
-			"--console",
-		)
+	settings, err := parseOverrides(overrides)
+	if err != nil {
+		return err
+	}
 
-		util.PrintLog("INFO").Println("MySQL starting...")
+	// on error the default locations are set all the same
+	layout, err = wamp.LoadLayout(dir, confOverride, settings)
+	if err == nil {
+		err = layout.Config.ValidateOverrides(layout)
+	}
 
-		err = mysqlProcess.Start()
-		if err != nil {
-			util.PrintLog("ERROR").Fatalf("MySQL unable to start. Error: %v\n", err)
-		}
+	wampDir = layout.WampDir
+	binDir = layout.BinDir
+	apacheDir = layout.ApacheDir()
+	mysqlDir = layout.MysqlDir()
+	phpDir = layout.PHPDir()
+	wwwDir = layout.WWWDir
+	tmpDir = layout.TmpDir
 
-		util.PrintLog("INFO").Println("MySQL started.")
-	})
+	return err
+}
 
-	mysqlStopCmd := cli.NewCommand("stop", "Stops MySQL", "", func(cmd *cli.Command, args []string) {
-		if err = loadConf(); err != nil {
-			util.PrintLog("ERROR").Fatalf("%v\n", err)
+// layoutOptional reports whether cmd runs without a valid layout: help,
+// shell completion and config, which is how a broken wamp.ini gets fixed.
+func layoutOptional(cmd *cli.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name {
+		case "help", "config", "completion", "__complete":
+			return true
 		}
+	}
 
-		util.PrintLog("INFO").Printf("Use MySQL: %s\n", activeMysql)
-
-		mysqlProcess := manager.New(
-			activeMysql,
-			path.Join(mysqlDir, activeMysql, "bin", "mysqld.exe"),
-			tmpDir,
-			"--console",
-		)
-
-		util.PrintLog("INFO").Println("MySQL stopping...")
+	return false
+}
 
-		err = mysqlProcess.Stop()
-		if err != nil {
-			util.PrintLog("ERROR").Fatalf("MySQL unable to stop. Error: %v\n", err)
-		}
+// loadConf sets the active versions, resolved from wamp.ini, the
+// environment and --set. wamp.ini must exist, i.e. the stack is installed.
+func loadConf() error {
+	if _, err := os.Stat(layout.ConfPath); err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
 
-		util.PrintLog("INFO").Println("MySQL stopped.")
-	})
-	mysqlCmd.AddCommands(mysqlStartCmd, mysqlStopCmd)
+	activeApache = layout.Config.Get("apache.active")
+	if activeApache == "" {
+		return errors.New("apache conf unavailable, set it with 'wamp config set apache.active <version>'")
+	}
 
-	siteCmd := cli.NewCommand("site", "Manages sites", "", nil)
-	siteAddCmd := cli.NewCommand("add", "Adds a site", "", func(cmd *cli.Command, args []string) {
-		// TODO: Validate sitename must include domain
-		// TODO: Accept project type (e.g., laravel, wordpress, moodle)
+	activeMysql = layout.Config.Get("mysql.active")
+	if activeMysql == "" {
+		return errors.New("mySQL conf unavailable, set it with 'wamp config set mysql.active <version>'")
+	}
 
-		if err = loadConf(); err != nil {
-			util.PrintLog("ERROR").Fatalf("%v\n", err)
-		}
+	return nil
+}
 
-		util.PrintLog("INFO").Printf("Use Apache: %s\n", activeApache)
-		util.PrintLog("INFO").Printf("Use MySQL: %s\n", activeMysql)
+func main() {
+	// Resolved again once the global flags are parsed, which reports the
+	// error; completion and plugin lookups may run before that.
+	resolveLayout("", "", nil)
 
-		util.PrintLog("INFO").Println("Creating site...")
-		sslEnable, err := strconv.ParseBool(*cmd.Flags["ssl"])
-		if err != nil {
-			util.PrintLog("ERROR").Fatalf("unable to parse ssl flag. Error: %v\n", err)
+	app := cli.NewCommand(
+		"wamp",
+		"Wamp CLI",
+		"Yet another Wamp stack manager",
+		func(cmd *cli.Command, args []string) error {
+			fmt.Println("Use 'help' to see available commands.")
+			return nil
+		},
+	)
+	app.Args = cli.NoArgs
+	app.PersistentPreRun = func(cmd *cli.Command, args []string) error {
+		if err := resolveLayout(cmd.GetString("wamp-dir"), cmd.GetString("config"), cmd.GetStringSlice("set")); err != nil {
+			if !layoutOptional(cmd) {
+				return fmt.Errorf("unable to load the wamp layout: %w", err)
+			}
+
+			util.PrintLog("WARN").Printf("Unable to load the wamp layout: %v\n", err)
+			return nil
 		}
 
-		// sslEnable := false
-		phpVersion, err := php.Search(phpDir, *cmd.Flags["php"])
-		if err != nil {
-			util.PrintLog("ERROR").Fatalf("unable to get php. Error: %v\n", err)
+		// Old stacks are only upgraded by wamp migrate; other commands
+		// check without writing anything and point there.
+		if cmd.Name != "migrate" && !layoutOptional(cmd) {
+			result, err := wamp.Migrate(layout, true)
+			if err != nil {
+				return err
+			}
+
+			if len(result.Applied) > 0 {
+				util.PrintLog("WARN").Printf("wamp.ini is at schema version %d, this wamp expects %d: %d migration(s) pending, run 'wamp migrate' to apply them\n", result.From, wamp.SchemaVersion, len(result.Applied))
+			}
 		}
 
-		util.PrintLog("INFO").Printf("Use PHP: %s\n", phpVersion)
-
-		sitename := args[0]
-		selectedPHPPath := path.Join(phpDir, phpVersion)
-		siteManager := site.New(wwwDir, path.Join(apacheDir, activeApache), selectedPHPPath, path.Join(binDir, "etc"))
+		util.PrintLog("INFO").Printf("Wamp dir: %s\n", wampDir)
+		return nil
+	}
 
-		if err := siteManager.Add(sitename, sslEnable); err != nil {
-			util.PrintLog("ERROR").Fatalf("unable to create site: %s. Error: %v\n", sitename, err)
+	initCmd := cli.NewCommand("init", "Initializes the application", "", func(cmd *cli.Command, args []string) error {
+		if err := wamp.New(layout).Init(); err != nil {
+			return err
 		}
 
-		util.PrintLog("INFO").Printf("Site '%s' created.\n", sitename)
+		cmd.SetResult(map[string]string{
+			"wamp_dir": wampDir,
+			"bin_dir":  binDir,
+			"www_dir":  wwwDir,
+			"tmp_dir":  tmpDir,
+		})
+
+		return nil
 	})
-	siteAddCmd.AddFlag("php", "p", "php-8.3", "The php version")
-	siteAddCmd.AddFlag("ssl", "s", "false", "Whether to use SSL")
+	initCmd.Args = cli.NoArgs
 
-	siteRmCmd := cli.NewCommand("rm", "Removes a site", "", func(cmd *cli.Command, args []string) {
-		if err = loadConf(); err != nil {
-			util.PrintLog("ERROR").Fatalf("%v\n", err)
+	installCmd := cli.NewCommand("install", "Installs the application", "", func(cmd *cli.Command, args []string) error {
+		if err := wamp.New(layout).Install(cmd.Context()); err != nil {
+			return err
 		}
 
-		util.PrintLog("INFO").Printf("Use Apache: %s\n", activeApache)
-		util.PrintLog("INFO").Printf("Use MySQL: %s\n", activeMysql)
-		util.PrintLog("INFO").Println("Removing site...")
+		cmd.SetResult(map[string]componentResult{
+			"php":    {Version: wamp.PHPVersion, Path: path.Join(phpDir, wamp.PHPVersion)},
+			"apache": {Version: wamp.ApacheVersion, Path: path.Join(apacheDir, wamp.ApacheVersion)},
+			"mysql":  {Version: wamp.MariaDBVersion, Path: path.Join(mysqlDir, wamp.MariaDBVersion)},
+		})
 
-		sitename := args[0]
-
-		siteManager := site.New(wwwDir, path.Join(apacheDir, activeApache), "", path.Join(binDir, "etc"))
+		return nil
+	})
+	installCmd.Args = cli.NoArgs
 
-		if err := siteManager.Remove(sitename); err != nil {
-			util.PrintLog("ERROR").Fatalf("unable to remove site: %s. Error: %v\n", sitename, err)
+	uninstallCmd := cli.NewCommand("uninstall", "Uninstall WAMP", "", func(cmd *cli.Command, args []string) error {
+		if err := wamp.New(layout).Clean(); err != nil {
+			return err
 		}
 
-		util.PrintLog("INFO").Printf("site: '%s' removed.\n", sitename)
-	})
-	siteCmd.AddCommands(siteAddCmd, siteRmCmd)
+		cmd.SetResult(map[string][]string{"removed": {binDir, tmpDir, wwwDir, layout.ConfPath}})
 
-	//php-8.4.9-nts-Win32-vs17-x64
-	phpCmd := cli.NewCommand("php", "Manages PHP", "Manage PHP instances", nil)
-	phpInstallCmd := cli.NewCommand("install", "Install specific PHP version", "", func(cmd *cli.Command, args []string) {
+		return nil
+	})
+	uninstallCmd.Args = cli.NoArgs
+	app.AddCommands(initCmd, installCmd, uninstallCmd)
 
-		phpManager := php.New(phpDir, tmpDir)
-		err = phpManager.Install(args[0])
-		if err != nil {
-			util.PrintLog("ERROR").Fatalf("failed to donwload php %s. Error %v\n", args[0], err)
+	apacheCmd := newApacheCmd()
+	mysqlCmd := newMysqlCmd()
+	siteCmd := newSiteCmd()
+	phpCmd := newPHPCmd()
+
+	app.AddCommands(apacheCmd, mysqlCmd, siteCmd, newParkCmd(), phpCmd, newConfigCmd(), newProfileCmd(), newMigrateCmd())
+	app.SetPluginHandler(pluginHandler(app))
+	cli.AddOutputFlag(app)
+	app.AddFlag("wamp-dir", "", "", "Root of the wamp stack (default: $WAMP_HOME or the directory of wamp.exe)")
+	app.MarkFlagPersistent("wamp-dir")
+	app.AddFlag("config", "", "", "Path to wamp.ini (default: $WAMP_CONFIG or <wamp-dir>/wamp.ini)")
+	app.MarkFlagPersistent("config")
+	app.AddStringSliceFlag("set", "", nil, "Override a wamp.ini setting for this run, e.g. --set apache.active=<version> (repeatable)")
+	app.MarkFlagPersistent("set")
+	app.RegisterFlagCompletion("set", func(cmd *cli.Command, args []string, toComplete string) []string {
+		names := []string{}
+		for _, name := range wamp.SettingNames() {
+			names = append(names, name+"=")
 		}
-
-		util.PrintLog("INFO").Printf("PHP %s installed", args[0])
+		return names
 	})
-	phpCmd.AddCommands(phpInstallCmd)
-
-	app.AddCommands(apacheCmd, mysqlCmd, siteCmd, phpCmd)
-	cli.AddHelpCommands(app, apacheCmd, mysqlCmd, siteCmd, phpCmd)
-	app.Execute()
+	cli.AddCompletionCommand(app)
+	cli.AddGenDocsCommand(app)
+	cli.AddHelpCommand(app)
+	// Ctrl+C cancels long running work such as downloads, which then
+	// clean up after themselves instead of dying halfway.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app.ExecuteContext(ctx)
 }
@@ -4,6 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/aziyan99/wamp/internal/util"
@@ -382,3 +385,122 @@ SSLSessionCacheTimeout  300
 #SSLStaplingErrorCacheTimeout 600
 	`
 }
+
+// SetPort makes the Apache in a2Root serve HTTP on port: the Listen and
+// ServerName directives of httpd.conf and the non-443 <VirtualHost *:N>
+// headers in sites-enabled are rewritten. Files already using port are
+// left untouched.
+func SetPort(a2Root, port string) error {
+	httpdConfPath := path.Join(a2Root, "conf", "httpd.conf")
+	err := RewriteLines(httpdConfPath, func(line string) string {
+		trimmedLine := strings.TrimSpace(line)
+		parts := strings.Fields(trimmedLine)
+		if len(parts) != 2 {
+			return line
+		}
+
+		identation := line[:strings.Index(line, trimmedLine)]
+		host, oldPort, hasHost := strings.Cut(parts[1], ":")
+		if !hasHost {
+			host, oldPort = "", parts[1]
+		}
+
+		if oldPort == "443" || oldPort == port {
+			return line
+		}
+
+		switch {
+		case parts[0] == "Listen" && hasHost:
+			return fmt.Sprintf("%sListen %s:%s", identation, host, port)
+		case parts[0] == "Listen":
+			return fmt.Sprintf("%sListen %s", identation, port)
+		case parts[0] == "ServerName" && hasHost:
+			return fmt.Sprintf("%sServerName %s:%s", identation, host, port)
+		}
+
+		return line
+	})
+	if err != nil {
+		return err
+	}
+
+	confs, err := filepath.Glob(path.Join(a2Root, "conf", "sites-enabled", "*.conf"))
+	if err != nil {
+		return err
+	}
+
+	for _, conf := range confs {
+		err = RewriteLines(conf, func(line string) string {
+			trimmedLine := strings.TrimSpace(line)
+			if !strings.HasPrefix(trimmedLine, "<VirtualHost *:") || strings.HasPrefix(trimmedLine, "<VirtualHost *:443>") {
+				return line
+			}
+
+			identation := line[:strings.Index(line, trimmedLine)]
+			return fmt.Sprintf("%s<VirtualHost *:%s>", identation, port)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RewriteLines passes every line of confPath through fn and writes the file
+// back when at least one line changed.
+func RewriteLines(confPath string, fn func(line string) string) error {
+	content, err := os.ReadFile(confPath)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	changed := false
+	for i, line := range lines {
+		trimmedLine := strings.TrimSuffix(line, "\r")
+		newLine := fn(trimmedLine)
+		if newLine != trimmedLine {
+			util.PrintLog("INFO").Printf("Updated %s\n--- %s\n+++ %s\n", path.Base(confPath), trimmedLine, newLine)
+			// keep CRLF line endings
+			lines[i] = newLine + strings.TrimPrefix(line, trimmedLine)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return os.WriteFile(confPath, []byte(strings.Join(lines, "\n")), 0644)
+}
+
+// ConfigTest checks the configuration of the Apache in a2Root with
+// httpd -t, returning its output as the error when the check fails.
+func ConfigTest(a2Root string) error {
+	output, err := exec.Command(path.Join(a2Root, "bin")+"\\httpd.exe", "-t").CombinedOutput()
+	if err != nil {
+		if len(output) > 0 {
+			return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Reload gracefully restarts the running Apache in a2Root with
+// httpd -k restart, the Windows equivalent of kill -USR1: requests in flight
+// are finished before the children pick up the new configuration, and the
+// parent process, i.e. the pid wamp recorded, keeps running.
+func Reload(a2Root string) error {
+	output, err := exec.Command(path.Join(a2Root, "bin")+"\\httpd.exe", "-k", "restart").CombinedOutput()
+	if err != nil {
+		if len(output) > 0 {
+			return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+		}
+		return err
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package apache
 
 import (
+	"context"
 	"errors"
 	"io/fs"
 	"os"
@@ -23,21 +24,31 @@ func New(apacheDir, tmpDir string) *Manager {
 	}
 }
 
-func (m *Manager) Install(a2Version, defaultPHPPath string) error {
-	var err error
+// Install downloads and sets up a2Version with mod_fcgid pointing at
+// defaultPHPPath. If it fails or ctx is cancelled the partially installed
+// version directory and the extracted mod_fcgid are removed again.
+func (m *Manager) Install(ctx context.Context, a2Version, defaultPHPPath string) (err error) {
+	fcgidVersion := "mod_fcgid-2.3.10-win64-VS17"
 
 	if _, err = os.Stat(path.Join(m.apacheDir, a2Version)); err == nil {
 		return errors.New("apache installation already exists")
 	}
 
+	defer func() {
+		if err != nil {
+			os.RemoveAll(path.Join(m.apacheDir, a2Version))
+			os.RemoveAll(path.Join(m.tmpDir, fcgidVersion))
+		}
+	}()
+
 	util.PrintLog("INFO").Println("Downloading Apache2...")
-	err = util.DownloadFile(path.Join(m.tmpDir, a2Version+".zip"), "https://www.apachelounge.com/download/VS17/binaries/"+a2Version+".zip")
+	err = util.DownloadFile(ctx, path.Join(m.tmpDir, a2Version+".zip"), "https://www.apachelounge.com/download/VS17/binaries/"+a2Version+".zip")
 	if err != nil {
 		return err
 	}
 
 	util.PrintLog("INFO").Println("Extracting Apache2...")
-	err = util.Unzip(path.Join(m.tmpDir, a2Version+".zip"), path.Join(m.apacheDir, a2Version))
+	err = util.Unzip(ctx, path.Join(m.tmpDir, a2Version+".zip"), path.Join(m.apacheDir, a2Version))
 	if err != nil {
 		return err
 	}
@@ -83,14 +94,13 @@ func (m *Manager) Install(a2Version, defaultPHPPath string) error {
 		return err
 	}
 
-	fcgidVersion := "mod_fcgid-2.3.10-win64-VS17"
 	util.PrintLog("INFO").Println("Downloading mod_fcgid...")
-	err = util.DownloadFile(path.Join(m.tmpDir, fcgidVersion+".zip"), "https://www.apachelounge.com/download/VS17/modules/"+fcgidVersion+".zip")
+	err = util.DownloadFile(ctx, path.Join(m.tmpDir, fcgidVersion+".zip"), "https://www.apachelounge.com/download/VS17/modules/"+fcgidVersion+".zip")
 	if err != nil {
 		return err
 	}
 
-	err = util.Unzip(path.Join(m.tmpDir, fcgidVersion+".zip"), path.Join(m.tmpDir, fcgidVersion))
+	err = util.Unzip(ctx, path.Join(m.tmpDir, fcgidVersion+".zip"), path.Join(m.tmpDir, fcgidVersion))
 	if err != nil {
 		return err
 	}
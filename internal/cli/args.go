@@ -0,0 +1,77 @@
+package cli
+
+import "fmt"
+
+// PositionalArgs validates the positional arguments left after flag parsing.
+type PositionalArgs func(cmd *Command, args []string) error
+
+// NoArgs rejects any positional argument.
+func NoArgs(cmd *Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected argument %q for %q", args[0], cmd.CommandPath())
+	}
+
+	return nil
+}
+
+// ArbitraryArgs accepts any number of positional arguments.
+func ArbitraryArgs(cmd *Command, args []string) error {
+	return nil
+}
+
+// ExactArgs requires exactly n positional arguments.
+func ExactArgs(n int) PositionalArgs {
+	return func(cmd *Command, args []string) error {
+		if len(args) != n {
+			return fmt.Errorf("accepts %d arg(s), received %d", n, len(args))
+		}
+
+		return nil
+	}
+}
+
+// MinimumNArgs requires at least n positional arguments.
+func MinimumNArgs(n int) PositionalArgs {
+	return func(cmd *Command, args []string) error {
+		if len(args) < n {
+			return fmt.Errorf("requires at least %d arg(s), received %d", n, len(args))
+		}
+
+		return nil
+	}
+}
+
+// MaximumNArgs accepts at most n positional arguments.
+func MaximumNArgs(n int) PositionalArgs {
+	return func(cmd *Command, args []string) error {
+		if len(args) > n {
+			return fmt.Errorf("accepts at most %d arg(s), received %d", n, len(args))
+		}
+
+		return nil
+	}
+}
+
+// RangeArgs requires between min and max positional arguments, inclusive.
+func RangeArgs(min, max int) PositionalArgs {
+	return func(cmd *Command, args []string) error {
+		if len(args) < min || len(args) > max {
+			return fmt.Errorf("accepts between %d and %d arg(s), received %d", min, max, len(args))
+		}
+
+		return nil
+	}
+}
+
+// MatchAll runs every validator in order and returns the first error.
+func MatchAll(validators ...PositionalArgs) PositionalArgs {
+	return func(cmd *Command, args []string) error {
+		for _, validate := range validators {
+			if err := validate(cmd, args); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
@@ -1,186 +1,346 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 )
 
 type Command struct {
-	Name        string
-	Short       string
-	Long        string
-	Run         func(cmd *Command, args []string)
-	SubCommands map[string]*Command
-	Flags       map[string]*string
+	Name      string
+	Aliases   []string
+	Short     string
+	Long      string
+	ArgsUsage string
+	Args      PositionalArgs
+	Hidden    bool
+	Run       func(cmd *Command, args []string) error
+	// PersistentPreRun runs before Run of this command and its children.
+	// Only the hook closest to the executed command runs.
+	PersistentPreRun func(cmd *Command, args []string) error
+	SubCommands      map[string]*Command
+	Flags            map[string]*Flag
+
+	// ValidArgsFunction returns the candidates for the next positional argument.
+	ValidArgsFunction CompletionFunc
+	// DisableFlagParsing passes every argument, flags included, to Run as is.
+	DisableFlagParsing bool
+
+	parent  *Command
+	result  any
+	plugins *PluginHandler
+	ctx     context.Context
 }
 
-func NewCommand(name, short, long string, run func(cmd *Command, args []string)) *Command {
+func NewCommand(name, short, long string, run func(cmd *Command, args []string) error) *Command {
 	cmd := &Command{
 		Name:        name,
 		Short:       short,
 		Long:        long,
 		Run:         run,
 		SubCommands: make(map[string]*Command),
-		Flags:       make(map[string]*string),
+		Flags:       make(map[string]*Flag),
 	}
 
 	return cmd
 }
 
 func (c *Command) AddCommand(cmd *Command) {
+	cmd.parent = c
 	c.SubCommands[cmd.Name] = cmd
 }
 
 func (c *Command) AddCommands(cmds ...*Command) {
 	for i := 0; i < len(cmds); i++ {
-		c.SubCommands[cmds[i].Name] = cmds[i]
+		c.AddCommand(cmds[i])
+	}
+}
+
+// findSubCommand returns the subcommand called name or having name as an alias.
+func (c *Command) findSubCommand(name string) *Command {
+	if sub, ok := c.SubCommands[name]; ok {
+		return sub
+	}
+
+	for _, sub := range c.Commands() {
+		if contains(sub.Aliases, name) {
+			return sub
+		}
 	}
+
+	return nil
 }
 
-func (c *Command) AddFlag(name, short, defaultValue, description string) {
-	c.Flags[name] = &defaultValue
+// Context returns the context given to ExecuteContext, or context.Background().
+func (c *Command) Context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+
+	if c.parent != nil {
+		return c.parent.Context()
+	}
+
+	return context.Background()
 }
 
-func (c *Command) Execute() {
-	args := os.Args[1:]
-
-	// Check for subcommands
-	if len(args) > 0 {
-		if cmd, ok := c.SubCommands[args[0]]; ok {
-			// Re-slice os.Args to pass to subcommand
-			os.Args = append([]string{os.Args[0]}, args[1:]...)
-			cmd.Execute()
-			return
+// Parent returns the command this one was added to, or nil for the root.
+func (c *Command) Parent() *Command {
+	return c.parent
+}
+
+// CommandPath returns the full invocation path, e.g. "wamp site add".
+func (c *Command) CommandPath() string {
+	if c.parent == nil {
+		return c.Name
+	}
+
+	return c.parent.CommandPath() + " " + c.Name
+}
+
+// UseLine returns the one-line usage of the command.
+func (c *Command) UseLine() string {
+	line := c.CommandPath()
+	if c.Run == nil && len(c.SubCommands) > 0 {
+		line += " <command>"
+	}
+	if c.ArgsUsage != "" {
+		line += " " + c.ArgsUsage
+	}
+	if len(c.allFlags()) > 0 {
+		line += " [flags]"
+	}
+
+	return line
+}
+
+// Commands returns the subcommands ordered by name.
+func (c *Command) Commands() []*Command {
+	names := make([]string, 0, len(c.SubCommands))
+	for name := range c.SubCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cmds := make([]*Command, 0, len(names))
+	for _, name := range names {
+		cmds = append(cmds, c.SubCommands[name])
+	}
+
+	return cmds
+}
+
+// VisibleCommands returns the subcommands that are not hidden, ordered by name.
+func (c *Command) VisibleCommands() []*Command {
+	cmds := []*Command{}
+	for _, sub := range c.Commands() {
+		if !sub.Hidden {
+			cmds = append(cmds, sub)
 		}
 	}
 
-	// Parse flags
-	parsedArgs := []string{}
+	return cmds
+}
+
+// Usage returns the usage text printed after an invocation error.
+func (c *Command) Usage() string {
+	var sb strings.Builder
+
+	sb.WriteString("Usage:\n  " + c.UseLine() + "\n")
+
+	if len(c.Aliases) > 0 {
+		sb.WriteString("\nAliases:\n  " + strings.Join(append([]string{c.Name}, c.Aliases...), ", ") + "\n")
+	}
+
+	if cmds := c.VisibleCommands(); len(cmds) > 0 {
+		longestName := 0
+		for _, sub := range cmds {
+			if len(sub.Name) > longestName {
+				longestName = len(sub.Name)
+			}
+		}
+
+		sb.WriteString("\nAvailable Commands:\n")
+		for _, sub := range cmds {
+			sb.WriteString(fmt.Sprintf("  %s%s  %s\n", sub.Name, strings.Repeat(" ", longestName-len(sub.Name)), sub.Short))
+		}
+	}
+
+	if len(c.Flags) > 0 {
+		sb.WriteString("\nFlags:\n")
+		sb.WriteString(c.FlagUsages())
+	}
+
+	if inherited := c.InheritedFlagUsages(); inherited != "" {
+		sb.WriteString("\nGlobal Flags:\n")
+		sb.WriteString(inherited)
+	}
+
+	return sb.String()
+}
+
+// find walks args down the command tree and returns the deepest matching
+// command together with the arguments left for it. Flags placed before a
+// subcommand name, such as "wamp --output json site add", are kept.
+func (c *Command) find(args []string) (*Command, []string) {
+	cmd := c
+	rest := []string{}
+
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
-		if len(arg) > 2 && arg[:2] == "--" {
-			flagName := arg[2:]
-			if _, ok := c.Flags[flagName]; ok {
-				if flagName == "ssl" {
-					*c.Flags[flagName] = "true"
-				} else if i+1 < len(args) {
-					*c.Flags[flagName] = args[i+1]
-					i++ // Skip the flag value
-				}
-			}
-		} else if len(arg) > 1 && arg[0] == '-' {
-			flagName := arg[1:]
-			if _, ok := c.Flags[flagName]; ok {
-				if flagName == "s" {
-					*c.Flags["ssl"] = "true"
-				} else if i+1 < len(args) {
-					*c.Flags[flagName] = args[i+1]
-					i++ // Skip the flag value
-				}
+
+		if arg == "--" || cmd.DisableFlagParsing {
+			return cmd, append(rest, args[i:]...)
+		}
+
+		if len(arg) > 1 && arg[0] == '-' {
+			rest = append(rest, arg)
+			f := cmd.lookupFlag(arg)
+			if f != nil && f.Type != BoolFlag && !strings.Contains(arg, "=") && i+1 < len(args) {
+				i++ // Keep the flag value with its flag
+				rest = append(rest, args[i])
 			}
-		} else {
-			parsedArgs = append(parsedArgs, arg)
+			continue
+		}
+
+		sub := cmd.findSubCommand(arg)
+		if sub == nil {
+			return cmd, append(rest, args[i:]...)
 		}
+
+		cmd = sub
 	}
 
-	c.Run(c, parsedArgs)
-
-	os.Exit(0)
-}
-
-func AddHelpCommand(cmd *Command) {
-	helpCmd := NewCommand(
-		"help",
-		"Prints help information",
-		"This command prints help information for a specific command.",
-		func(c *Command, args []string) {
-			if len(args) == 0 {
-				fmt.Println(cmd.Long)
-				fmt.Println("\nAvailable Commands:")
-
-				// Find the longest command name
-				longestName := 0
-				for _, subCmd := range cmd.SubCommands {
-					if len(subCmd.Name) > longestName {
-						longestName = len(subCmd.Name)
-					}
-					for _, subSubCmd := range subCmd.SubCommands {
-						if len(subCmd.Name+":"+subSubCmd.Name) > longestName {
-							longestName = len(subCmd.Name + ":" + subSubCmd.Name)
-						}
-					}
-				}
+	return cmd, rest
+}
 
-				for _, subCmd := range cmd.SubCommands {
-					padding := longestName - len(subCmd.Name)
-					if padding < 0 {
-						padding = 0
-					}
-					fmt.Printf("  %s%s  %s\n", subCmd.Name, strings.Repeat(" ", padding), subCmd.Short)
-					if len(subCmd.SubCommands) > 0 {
-						for _, subSubCmd := range subCmd.SubCommands {
-							name := subCmd.Name + " " + subSubCmd.Name
-							padding := longestName - len(name)
-							if padding < 0 {
-								padding = 0
-							}
-							fmt.Printf("    %s%s  %s\n", name, strings.Repeat(" ", padding), subSubCmd.Short)
-						}
-						fmt.Println()
-					}
-				}
-				return
-			}
+// ExecuteArgs runs the command matching args. Any failure is returned as *Error.
+func (c *Command) ExecuteArgs(args []string) error {
+	_, err := c.execute(args)
+	return err
+}
 
-			// try to find the command
-			var cmdToHelp *Command
-			for _, subCmd := range cmd.SubCommands {
-				if subCmd.Name == args[0] {
-					cmdToHelp = subCmd
-					break
-				}
-				for _, subSubCmd := range subCmd.SubCommands {
-					if subSubCmd.Name == args[0] {
-						cmdToHelp = subSubCmd
-						break
-					}
+// execute runs the command matching args and returns it with the outcome.
+func (c *Command) execute(args []string) (*Command, error) {
+	if c.plugins != nil {
+		// Global flags before the plugin name are parsed first so Dirs and
+		// Env can honour them; on a parse error the normal path reports it.
+		if name, leading, pluginArgs, ok := c.pluginCommand(args); ok {
+			if _, err := c.parseFlags(leading); err == nil {
+				if p := c.plugins.Find(name); p != "" {
+					return c, c.runPlugin(p, pluginArgs)
 				}
 			}
+		}
+	}
 
-			if cmdToHelp == nil {
-				fmt.Printf("Unknown command: %s\n", args[0])
-				return
-			}
+	cmd, args := c.find(args)
 
-			fmt.Println(cmdToHelp.Long)
-			fmt.Println("\nUsage:")
-			fmt.Printf("  %s %s [flags]\n", cmd.Name, cmdToHelp.Name)
-
-			if len(cmdToHelp.Flags) > 0 {
-				fmt.Println("\nFlags:")
-				// Find the longest flag name
-				longestFlagName := 0
-				for name := range cmdToHelp.Flags {
-					if len(name) > longestFlagName {
-						longestFlagName = len(name)
-					}
-				}
-				for name, flag := range cmdToHelp.Flags {
-					padding := longestFlagName - len(name)
-					if padding < 0 {
-						padding = 0
-					}
-					fmt.Printf("  --%s%s  %s (default: \"%s\")\n", name, strings.Repeat(" ", padding), "", *flag)
-				}
+	if !cmd.DisableFlagParsing && wantsHelp(args) {
+		fmt.Print(cmd.HelpText())
+		return cmd, nil
+	}
+
+	parsedArgs := args
+	if !cmd.DisableFlagParsing {
+		var err error
+		if parsedArgs, err = cmd.parseFlags(args); err != nil {
+			return cmd, &Error{Code: ExitFlag, Cmd: cmd, Err: err}
+		}
+
+		if err := validateOutputFormat(cmd); err != nil {
+			return cmd, &Error{Code: ExitFlag, Cmd: cmd, Err: err}
+		}
+	}
+
+	var argsErr error
+	if cmd.Args != nil {
+		argsErr = cmd.Args(cmd, parsedArgs)
+	}
+
+	// A word that is neither a subcommand nor an accepted argument is a
+	// mistyped command, never something to silently hand to Run.
+	if len(parsedArgs) > 0 && len(cmd.SubCommands) > 0 && (cmd.Run == nil || argsErr != nil) {
+		return cmd, unknownCommandError(cmd, parsedArgs[0])
+	}
+
+	if cmd.Run == nil {
+		return cmd, newError(ExitUsage, cmd, "%q requires a subcommand", cmd.CommandPath())
+	}
+
+	if argsErr != nil {
+		return cmd, &Error{Code: ExitUsage, Cmd: cmd, Err: argsErr}
+	}
+
+	for p := cmd; p != nil; p = p.parent {
+		if p.PersistentPreRun != nil {
+			if err := p.PersistentPreRun(cmd, parsedArgs); err != nil {
+				return cmd, &Error{Code: ExitFailure, Cmd: cmd, Err: err}
 			}
-		},
-	)
-	cmd.AddCommand(helpCmd)
+			break
+		}
+	}
+
+	if err := cmd.Run(cmd, parsedArgs); err != nil {
+		var cliErr *Error
+		if errors.As(err, &cliErr) {
+			return cmd, cliErr
+		}
+
+		// Killed child processes do not report context.Canceled themselves
+		if errors.Is(err, context.Canceled) || cmd.Context().Err() != nil {
+			return cmd, &Error{Code: ExitCancelled, Cmd: cmd, Err: err}
+		}
+
+		return cmd, &Error{Code: ExitFailure, Cmd: cmd, Err: err}
+	}
+
+	return cmd, nil
 }
 
-func AddHelpCommands(cmds ...*Command) {
-	for i := 0; i < len(cmds); i++ {
-		AddHelpCommand(cmds[i])
+// Execute runs the command tree against os.Args and exits the process.
+// Invocation errors print the usage of the offending command; every kind
+// of failure exits with its own code (see ExitUsage and friends). With
+// "--output json" the result or error is printed as JSON instead.
+func (c *Command) Execute() {
+	c.ExecuteContext(context.Background())
+}
+
+// ExecuteContext is Execute with a context, usually cancelled on Ctrl+C.
+// Commands read it through Context.
+func (c *Command) ExecuteContext(ctx context.Context) {
+	c.ctx = ctx
+	cmd, err := c.execute(os.Args[1:])
+
+	code := ExitOK
+	var cliErr *Error
+	if errors.As(err, &cliErr) {
+		code = cliErr.Code
+	} else if err != nil {
+		code = ExitFailure
 	}
+
+	if cliErr != nil && cliErr.silent {
+		os.Exit(code)
+	}
+
+	if cmd.OutputFormat() == OutputJSON {
+		if jsonErr := writeJSON(os.Stdout, cmd, err); jsonErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", jsonErr)
+		}
+		os.Exit(code)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+
+		if cliErr != nil && cliErr.IsUsage() {
+			fmt.Fprintf(os.Stderr, "\n%s", cliErr.Cmd.Usage())
+		}
+	}
+
+	os.Exit(code)
 }
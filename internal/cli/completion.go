@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CompletionFunc returns the candidates for the word being completed.
+// args holds the positional arguments already typed for cmd.
+type CompletionFunc func(cmd *Command, args []string, toComplete string) []string
+
+// Completion is a single candidate printed by the hidden __complete command.
+type Completion struct {
+	Value       string
+	Description string
+}
+
+// RegisterFlagCompletion sets the function completing the values of a flag.
+func (c *Command) RegisterFlagCompletion(name string, fn CompletionFunc) {
+	c.mustFlag(name).Complete = fn
+}
+
+// Complete returns the candidates for the last word of words, which are the
+// arguments typed after the program name. The last word may be empty.
+func (c *Command) Complete(words []string) []Completion {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	toComplete := words[len(words)-1]
+	preceding := words[:len(words)-1]
+
+	cmd := c
+	args := []string{}
+	var valueFlag *Flag
+
+	for i := 0; i < len(preceding); i++ {
+		word := preceding[i]
+
+		if len(word) > 1 && word[0] == '-' {
+			f := cmd.lookupFlag(word)
+			if f == nil || f.Type == BoolFlag || strings.Contains(word, "=") {
+				continue
+			}
+
+			// bash splits "--flag=value" into "--flag", "=", "value"
+			if i+1 < len(preceding) && preceding[i+1] == "=" {
+				i++
+			}
+
+			if i+1 < len(preceding) {
+				i++ // Skip the flag value
+				continue
+			}
+
+			valueFlag = f
+			continue
+		}
+
+		if word == "=" {
+			continue
+		}
+
+		if len(args) == 0 {
+			if sub := cmd.findSubCommand(word); sub != nil {
+				cmd = sub
+				continue
+			}
+		}
+
+		args = append(args, word)
+	}
+
+	completions := []Completion{}
+
+	switch {
+	case valueFlag != nil:
+		if valueFlag.Complete != nil {
+			for _, v := range valueFlag.Complete(cmd, args, toComplete) {
+				completions = append(completions, Completion{Value: v})
+			}
+		}
+	case strings.HasPrefix(toComplete, "-") && strings.Contains(toComplete, "="):
+		name, _, _ := strings.Cut(toComplete, "=")
+		if f := cmd.lookupFlag(name); f != nil && f.Complete != nil {
+			for _, v := range f.Complete(cmd, args, toComplete) {
+				completions = append(completions, Completion{Value: name + "=" + v})
+			}
+		}
+	case strings.HasPrefix(toComplete, "-"):
+		for _, f := range cmd.allFlags() {
+			completions = append(completions, Completion{Value: "--" + f.Name, Description: f.Description})
+		}
+	default:
+		if len(args) == 0 {
+			names := []string{}
+			for name, sub := range cmd.SubCommands {
+				if !sub.Hidden {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				completions = append(completions, Completion{Value: name, Description: cmd.SubCommands[name].Short})
+			}
+
+			for _, p := range cmd.Plugins() {
+				completions = append(completions, Completion{Value: p.Name, Description: "plugin"})
+			}
+		}
+
+		if cmd.ValidArgsFunction != nil {
+			for _, v := range cmd.ValidArgsFunction(cmd, args, toComplete) {
+				completions = append(completions, Completion{Value: v})
+			}
+		}
+	}
+
+	filtered := []Completion{}
+	for _, comp := range completions {
+		if strings.HasPrefix(comp.Value, toComplete) {
+			filtered = append(filtered, comp)
+		}
+	}
+
+	return filtered
+}
+
+// AddCompletionCommand adds "completion <shell>", which prints the completion
+// script, and the hidden "__complete" command the scripts call back into.
+func AddCompletionCommand(root *Command) {
+	generators := map[string]func(w io.Writer, root *Command) error{
+		"bash":       GenBashCompletion,
+		"zsh":        GenZshCompletion,
+		"fish":       GenFishCompletion,
+		"powershell": GenPowerShellCompletion,
+	}
+
+	completionCmd := NewCommand(
+		"completion",
+		"Generates shell completion scripts",
+		"Prints a completion script for bash, zsh, fish or powershell. Load it from your shell profile, e.g.\n  wamp completion powershell | Out-String | Invoke-Expression",
+		func(c *Command, args []string) error {
+			gen, ok := generators[args[0]]
+			if !ok {
+				return fmt.Errorf("unsupported shell %q", args[0])
+			}
+
+			return gen(os.Stdout, root)
+		},
+	)
+	completionCmd.ArgsUsage = "<bash|zsh|fish|powershell>"
+	completionCmd.Args = ExactArgs(1)
+	completionCmd.ValidArgsFunction = func(cmd *Command, args []string, toComplete string) []string {
+		if len(args) > 0 {
+			return nil
+		}
+
+		return []string{"bash", "fish", "powershell", "zsh"}
+	}
+
+	completeCmd := NewCommand("__complete", "Prints completion candidates", "", func(c *Command, args []string) error {
+		// PowerShell drops empty arguments, so the scripts send "" instead.
+		if len(args) > 0 && args[len(args)-1] == `""` {
+			args[len(args)-1] = ""
+		}
+
+		for _, comp := range root.Complete(args) {
+			if comp.Description != "" {
+				fmt.Printf("%s\t%s\n", comp.Value, comp.Description)
+			} else {
+				fmt.Println(comp.Value)
+			}
+		}
+
+		return nil
+	})
+	completeCmd.Hidden = true
+	completeCmd.DisableFlagParsing = true
+
+	// Anything the root hook prints would end up in the shell, so skip it.
+	noop := func(cmd *Command, args []string) error { return nil }
+	completionCmd.PersistentPreRun = noop
+	completeCmd.PersistentPreRun = noop
+
+	root.AddCommands(completionCmd, completeCmd)
+}
+
+// GenBashCompletion writes a bash completion script for root.
+func GenBashCompletion(w io.Writer, root *Command) error {
+	_, err := fmt.Fprintf(w, `# bash completion for %[1]s
+
+_%[1]s_complete() {
+    local IFS=$'\n'
+    local out
+    out=$("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null) || return
+    COMPREPLY=( $(printf '%%s\n' "$out" | cut -f1) )
+}
+
+complete -o default -F _%[1]s_complete %[1]s %[1]s.exe
+`, root.Name)
+
+	return err
+}
+
+// GenZshCompletion writes a zsh completion script for root.
+func GenZshCompletion(w io.Writer, root *Command) error {
+	_, err := fmt.Fprintf(w, `#compdef %[1]s %[1]s.exe
+
+_%[1]s() {
+    local -a completions
+    local line value desc
+    for line in "${(@f)$(${words[1]} __complete "${(@)words[2,$CURRENT]}" 2>/dev/null)}"; do
+        [[ -z $line ]] && continue
+        value=${line%%%%$'\t'*}
+        desc=${line#*$'\t'}
+        completions+=("${value//:/\\:}:${desc}")
+    done
+    _describe '%[1]s' completions
+}
+
+compdef _%[1]s %[1]s %[1]s.exe
+`, root.Name)
+
+	return err
+}
+
+// GenFishCompletion writes a fish completion script for root.
+func GenFishCompletion(w io.Writer, root *Command) error {
+	_, err := fmt.Fprintf(w, `# fish completion for %[1]s
+
+function __%[1]s_complete
+    set -l tokens (commandline -opc)
+    $tokens[1] __complete $tokens[2..-1] (commandline -ct) 2>/dev/null
+end
+
+complete -c %[1]s -f -a '(__%[1]s_complete)'
+complete -c %[1]s.exe -f -a '(__%[1]s_complete)'
+`, root.Name)
+
+	return err
+}
+
+// GenPowerShellCompletion writes a PowerShell completion script for root.
+func GenPowerShellCompletion(w io.Writer, root *Command) error {
+	_, err := fmt.Fprintf(w, `# powershell completion for %[1]s
+
+Register-ArgumentCompleter -Native -CommandName '%[1]s', '%[1]s.exe' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $words = @($commandAst.CommandElements |
+        Where-Object { $_.Extent.StartOffset -lt $cursorPosition } |
+        ForEach-Object { $_.ToString() })
+    $program = $words[0]
+    $words = @($words | Select-Object -Skip 1)
+    if ($wordToComplete -eq '') {
+        $words += '""'
+    }
+
+    & $program __complete @words 2>$null | ForEach-Object {
+        $value, $desc = $_ -split "`+"`t"+`", 2
+        if (-not $desc) { $desc = $value }
+        [System.Management.Automation.CompletionResult]::new($value, $value, 'ParameterValue', $desc)
+    }
+}
+`, root.Name)
+
+	return err
+}
@@ -0,0 +1,254 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// visibleTree returns root and every visible command below it, depth first
+// and sorted by name at every level.
+func visibleTree(root *Command) []*Command {
+	cmds := []*Command{root}
+	for _, sub := range root.VisibleCommands() {
+		cmds = append(cmds, visibleTree(sub)...)
+	}
+
+	return cmds
+}
+
+func markdownFlagTable(w io.Writer, title string, flags []*Flag) {
+	if len(flags) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "**%s**\n\n", title)
+	fmt.Fprintln(w, "| Flag | Type | Default | Description |")
+	fmt.Fprintln(w, "|------|------|---------|-------------|")
+	for _, f := range flags {
+		name := "--" + f.Name
+		if f.Short != "" {
+			name = "-" + f.Short + ", --" + f.Name
+		}
+
+		def := ""
+		if f.Required {
+			def = "required"
+		} else if f.hasDefault() {
+			def = "`" + f.Default + "`"
+		}
+
+		fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", name, f.Type, def, strings.ReplaceAll(f.Description, "|", `\|`))
+	}
+	fmt.Fprintln(w)
+}
+
+// GenMarkdown writes a reference of the whole command tree as one Markdown document.
+func GenMarkdown(w io.Writer, root *Command) error {
+	fmt.Fprintf(w, "# %s command reference\n\n", root.Name)
+	fmt.Fprintf(w, "<!-- Generated by `%s gen-docs`. DO NOT EDIT. -->\n\n", root.Name)
+
+	for _, cmd := range visibleTree(root) {
+		fmt.Fprintf(w, "## %s\n\n", cmd.CommandPath())
+
+		if cmd.Short != "" {
+			fmt.Fprintf(w, "%s\n\n", cmd.Short)
+		}
+		if cmd.Long != "" && cmd.Long != cmd.Short {
+			fmt.Fprintf(w, "%s\n\n", cmd.Long)
+		}
+
+		fmt.Fprintf(w, "```\n%s\n```\n\n", cmd.UseLine())
+
+		if len(cmd.Aliases) > 0 {
+			fmt.Fprintf(w, "Aliases: `%s`\n\n", strings.Join(cmd.Aliases, "`, `"))
+		}
+
+		if subs := cmd.VisibleCommands(); len(subs) > 0 {
+			fmt.Fprintln(w, "**Commands**")
+			fmt.Fprintln(w)
+			for _, sub := range subs {
+				anchor := strings.ReplaceAll(sub.CommandPath(), " ", "-")
+				fmt.Fprintf(w, "- [`%s`](#%s) - %s\n", sub.CommandPath(), anchor, sub.Short)
+			}
+			fmt.Fprintln(w)
+		}
+
+		markdownFlagTable(w, "Flags", cmd.sortedFlags())
+		markdownFlagTable(w, "Global flags", cmd.inheritedFlags())
+	}
+
+	return nil
+}
+
+// manEscape escapes text for roff.
+func manEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func manFlags(w io.Writer, flags []*Flag) {
+	for _, f := range flags {
+		fmt.Fprintln(w, ".TP")
+
+		name := `\fB\-\-` + manEscape(f.Name) + `\fR`
+		if f.Short != "" {
+			name = `\fB\-` + manEscape(f.Short) + `\fR, ` + name
+		}
+		if f.Type != BoolFlag {
+			name += `=\fI` + f.Type.String() + `\fR`
+		}
+		fmt.Fprintln(w, name)
+
+		description := f.Description
+		if f.Required {
+			description += " (required)"
+		} else if f.hasDefault() {
+			description += fmt.Sprintf(" (default: %q)", f.Default)
+		}
+		fmt.Fprintln(w, manEscape(description))
+	}
+}
+
+// GenManPage writes the man page of a single command.
+func GenManPage(w io.Writer, cmd *Command) error {
+	name := strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+
+	fmt.Fprintf(w, ".TH \"%s\" \"1\" \"\" \"%s\" \"%s Manual\"\n", strings.ToUpper(manEscape(name)), cmd.root().Name, cmd.root().Name)
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintf(w, "%s \\- %s\n", manEscape(name), manEscape(cmd.Short))
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintf(w, ".B %s\n", manEscape(cmd.CommandPath()))
+	if rest := strings.TrimPrefix(cmd.UseLine(), cmd.CommandPath()); rest != "" {
+		fmt.Fprintln(w, manEscape(strings.TrimSpace(rest)))
+	}
+
+	if cmd.Long != "" {
+		fmt.Fprintln(w, ".SH DESCRIPTION")
+		fmt.Fprintln(w, manEscape(cmd.Long))
+	}
+
+	if flags := cmd.sortedFlags(); len(flags) > 0 {
+		fmt.Fprintln(w, ".SH OPTIONS")
+		manFlags(w, flags)
+	}
+
+	if flags := cmd.inheritedFlags(); len(flags) > 0 {
+		fmt.Fprintln(w, ".SH GLOBAL OPTIONS")
+		manFlags(w, flags)
+	}
+
+	related := []string{}
+	if cmd.parent != nil {
+		related = append(related, strings.ReplaceAll(cmd.parent.CommandPath(), " ", "-"))
+	}
+	for _, sub := range cmd.VisibleCommands() {
+		related = append(related, strings.ReplaceAll(sub.CommandPath(), " ", "-"))
+	}
+
+	if len(related) > 0 {
+		fmt.Fprintln(w, ".SH SEE ALSO")
+		refs := make([]string, len(related))
+		for i, r := range related {
+			refs[i] = `\fB` + manEscape(r) + `\fR(1)`
+		}
+		fmt.Fprintln(w, strings.Join(refs, ", "))
+	}
+
+	return nil
+}
+
+// GenManPages writes one "<command-path>.1" man page per visible command into dir.
+func GenManPages(dir string, root *Command) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, cmd := range visibleTree(root) {
+		name := strings.ReplaceAll(cmd.CommandPath(), " ", "-") + ".1"
+
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+
+		if err := GenManPage(f, cmd); err != nil {
+			f.Close()
+			return err
+		}
+
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Command) root() *Command {
+	if c.parent == nil {
+		return c
+	}
+
+	return c.parent.root()
+}
+
+// AddGenDocsCommand adds the hidden "gen-docs" command, which renders the
+// command tree to Markdown and man pages.
+func AddGenDocsCommand(root *Command) {
+	genDocsCmd := NewCommand(
+		"gen-docs",
+		"Generates the command reference",
+		"Renders every command, flag and default to <dir>/cli.md and man pages to <dir>/man.",
+		func(c *Command, args []string) error {
+			dir := c.GetString("dir")
+			format := c.GetString("format")
+
+			if format != "markdown" && format != "man" && format != "all" {
+				return fmt.Errorf("invalid format %q, expected markdown, man or all", format)
+			}
+
+			if format == "markdown" || format == "all" {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return err
+				}
+
+				f, err := os.Create(filepath.Join(dir, "cli.md"))
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+
+				if err := GenMarkdown(f, root); err != nil {
+					return err
+				}
+			}
+
+			if format == "man" || format == "all" {
+				if err := GenManPages(filepath.Join(dir, "man"), root); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	)
+	genDocsCmd.Hidden = true
+	genDocsCmd.Args = NoArgs
+	genDocsCmd.AddFlag("dir", "d", "docs", "Output directory")
+	genDocsCmd.AddFlag("format", "f", "all", "markdown, man or all")
+	genDocsCmd.PersistentPreRun = func(c *Command, args []string) error { return nil }
+
+	root.AddCommand(genDocsCmd)
+}
@@ -0,0 +1,42 @@
+package cli
+
+import "fmt"
+
+// Exit codes returned by Execute. Wrapper scripts rely on them to tell
+// bad usage apart from a command that failed while running.
+const (
+	ExitOK             = 0
+	ExitFailure        = 1   // Run returned an error
+	ExitUsage          = 2   // positional arguments rejected by Args
+	ExitFlag           = 3   // unknown, malformed or missing required flag
+	ExitUnknownCommand = 4   // no such command
+	ExitCancelled      = 130 // interrupted, e.g. by Ctrl+C
+)
+
+// Error is returned by ExecuteArgs. It carries the exit code and the
+// command that was being executed so the usage message can be printed.
+type Error struct {
+	Code int
+	Cmd  *Command
+	Err  error
+
+	// silent errors already reported themselves, e.g. a plugin exit status.
+	silent bool
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsUsage reports whether the error was caused by the invocation rather than the command itself.
+func (e *Error) IsUsage() bool {
+	return e.Code == ExitUsage || e.Code == ExitFlag || e.Code == ExitUnknownCommand
+}
+
+func newError(code int, cmd *Command, format string, a ...any) *Error {
+	return &Error{Code: code, Cmd: cmd, Err: fmt.Errorf(format, a...)}
+}
@@ -0,0 +1,378 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FlagType identifies how the raw value of a flag is parsed.
+type FlagType int
+
+const (
+	StringFlag FlagType = iota
+	BoolFlag
+	IntFlag
+	DurationFlag
+	StringSliceFlag
+)
+
+func (t FlagType) String() string {
+	switch t {
+	case BoolFlag:
+		return "bool"
+	case IntFlag:
+		return "int"
+	case DurationFlag:
+		return "duration"
+	case StringSliceFlag:
+		return "strings"
+	default:
+		return "string"
+	}
+}
+
+// Flag describes a single command line flag and holds its parsed value.
+type Flag struct {
+	Name        string
+	Short       string
+	Description string
+	Type        FlagType
+	Default     string
+	Required    bool
+	Complete    CompletionFunc
+	// Persistent flags are inherited by every subcommand.
+	Persistent bool
+
+	value   any
+	changed bool
+}
+
+// reset restores the flag to its default value.
+func (f *Flag) reset() {
+	f.changed = false
+
+	switch f.Type {
+	case BoolFlag:
+		f.value, _ = strconv.ParseBool(f.Default)
+	case IntFlag:
+		f.value, _ = strconv.Atoi(f.Default)
+	case DurationFlag:
+		f.value, _ = time.ParseDuration(f.Default)
+	case StringSliceFlag:
+		if f.Default == "" {
+			f.value = []string{}
+		} else {
+			f.value = strings.Split(f.Default, ",")
+		}
+	default:
+		f.value = f.Default
+	}
+}
+
+// hasDefault reports whether the default is worth showing in help, i.e. it is not a zero value.
+func (f *Flag) hasDefault() bool {
+	return f.Default != "" && f.Default != "false" && f.Default != "0" && f.Default != "0s"
+}
+
+// set parses raw according to the flag type and stores it.
+// Slice flags append every occurrence, replacing the default on the first one.
+func (f *Flag) set(raw string) error {
+	switch f.Type {
+	case BoolFlag:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean value %q for flag --%s", raw, f.Name)
+		}
+		f.value = v
+	case IntFlag:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer value %q for flag --%s", raw, f.Name)
+		}
+		f.value = v
+	case DurationFlag:
+		v, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration value %q for flag --%s", raw, f.Name)
+		}
+		f.value = v
+	case StringSliceFlag:
+		if !f.changed {
+			f.value = []string{}
+		}
+		f.value = append(f.value.([]string), raw)
+	default:
+		f.value = raw
+	}
+
+	f.changed = true
+
+	return nil
+}
+
+func (c *Command) addFlag(f *Flag) {
+	if _, ok := c.Flags[f.Name]; ok {
+		panic(fmt.Sprintf("flag redefined: %s", f.Name))
+	}
+
+	if f.Short != "" && c.lookupShortFlag(f.Short) != nil {
+		panic(fmt.Sprintf("flag shorthand redefined: %s", f.Short))
+	}
+
+	f.reset()
+	c.Flags[f.Name] = f
+}
+
+// AddFlag defines a string flag.
+func (c *Command) AddFlag(name, short, defaultValue, description string) {
+	c.addFlag(&Flag{Name: name, Short: short, Description: description, Type: StringFlag, Default: defaultValue})
+}
+
+// AddBoolFlag defines a boolean flag. It is set to true when given without a value.
+func (c *Command) AddBoolFlag(name, short string, defaultValue bool, description string) {
+	c.addFlag(&Flag{Name: name, Short: short, Description: description, Type: BoolFlag, Default: strconv.FormatBool(defaultValue)})
+}
+
+// AddIntFlag defines an integer flag.
+func (c *Command) AddIntFlag(name, short string, defaultValue int, description string) {
+	c.addFlag(&Flag{Name: name, Short: short, Description: description, Type: IntFlag, Default: strconv.Itoa(defaultValue)})
+}
+
+// AddDurationFlag defines a flag parsed with time.ParseDuration (e.g. 30s, 5m).
+func (c *Command) AddDurationFlag(name, short string, defaultValue time.Duration, description string) {
+	c.addFlag(&Flag{Name: name, Short: short, Description: description, Type: DurationFlag, Default: defaultValue.String()})
+}
+
+// AddStringSliceFlag defines a flag that may be repeated to collect several values.
+func (c *Command) AddStringSliceFlag(name, short string, defaultValue []string, description string) {
+	c.addFlag(&Flag{Name: name, Short: short, Description: description, Type: StringSliceFlag, Default: strings.Join(defaultValue, ",")})
+}
+
+// MarkFlagRequired makes Execute fail when the flag is not given.
+func (c *Command) MarkFlagRequired(name string) {
+	c.mustFlag(name).Required = true
+}
+
+// MarkFlagPersistent makes the flag available to every subcommand.
+func (c *Command) MarkFlagPersistent(name string) {
+	c.mustFlag(name).Persistent = true
+}
+
+func (c *Command) mustFlag(name string) *Flag {
+	f := c.findFlag(name)
+	if f == nil {
+		panic(fmt.Sprintf("flag not defined: %s", name))
+	}
+
+	return f
+}
+
+// findFlag returns the local flag called name or a persistent one inherited from a parent.
+func (c *Command) findFlag(name string) *Flag {
+	if f, ok := c.Flags[name]; ok {
+		return f
+	}
+
+	for p := c.parent; p != nil; p = p.parent {
+		if f, ok := p.Flags[name]; ok && f.Persistent {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func (c *Command) lookupShortFlag(short string) *Flag {
+	for _, f := range c.allFlags() {
+		if f.Short == short {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// lookupFlag resolves a "--name[=value]" or "-s[=value]" argument to its flag.
+func (c *Command) lookupFlag(arg string) *Flag {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	if strings.HasPrefix(arg, "--") {
+		return c.findFlag(name)
+	}
+
+	return c.lookupShortFlag(name)
+}
+
+// inheritedFlags returns the persistent flags of the parents, ordered by name.
+func (c *Command) inheritedFlags() []*Flag {
+	flags := []*Flag{}
+	for p := c.parent; p != nil; p = p.parent {
+		for _, f := range p.Flags {
+			if f.Persistent && c.Flags[f.Name] == nil {
+				flags = append(flags, f)
+			}
+		}
+	}
+
+	sortFlags(flags)
+
+	return flags
+}
+
+// allFlags returns the local and inherited flags, ordered by name.
+func (c *Command) allFlags() []*Flag {
+	flags := append(c.sortedFlags(), c.inheritedFlags()...)
+	sortFlags(flags)
+
+	return flags
+}
+
+// Changed reports whether the flag was explicitly given on the command line.
+func (c *Command) Changed(name string) bool {
+	return c.mustFlag(name).changed
+}
+
+func (c *Command) GetString(name string) string {
+	return fmt.Sprint(c.mustFlag(name).value)
+}
+
+func (c *Command) GetBool(name string) bool {
+	v, _ := c.mustFlag(name).value.(bool)
+	return v
+}
+
+func (c *Command) GetInt(name string) int {
+	v, _ := c.mustFlag(name).value.(int)
+	return v
+}
+
+func (c *Command) GetDuration(name string) time.Duration {
+	v, _ := c.mustFlag(name).value.(time.Duration)
+	return v
+}
+
+func (c *Command) GetStringSlice(name string) []string {
+	v, _ := c.mustFlag(name).value.([]string)
+	return append([]string(nil), v...)
+}
+
+// parseFlags sets the flags found in args and returns the remaining positional arguments.
+// Everything after a bare "--" is treated as positional.
+func (c *Command) parseFlags(args []string) ([]string, error) {
+	for _, f := range c.allFlags() {
+		f.reset()
+	}
+
+	positional := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+
+		if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, arg)
+			continue
+		}
+
+		display, value, hasValue := strings.Cut(arg, "=")
+		f := c.lookupFlag(arg)
+		if f == nil {
+			return nil, unknownFlagError(c, arg)
+		}
+
+		if !hasValue {
+			if f.Type == BoolFlag {
+				value = "true"
+			} else if i+1 < len(args) {
+				value = args[i+1]
+				i++ // Skip the flag value
+			} else {
+				return nil, fmt.Errorf("flag needs an argument: %s", display)
+			}
+		}
+
+		if err := f.set(value); err != nil {
+			return nil, err
+		}
+	}
+
+	missing := []string{}
+	for _, f := range c.allFlags() {
+		if f.Required && !f.changed {
+			missing = append(missing, "--"+f.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, errors.New("required flag(s) not set: " + strings.Join(missing, ", "))
+	}
+
+	return positional, nil
+}
+
+// sortedFlags returns the command flags ordered by name.
+func (c *Command) sortedFlags() []*Flag {
+	flags := make([]*Flag, 0, len(c.Flags))
+	for _, f := range c.Flags {
+		flags = append(flags, f)
+	}
+
+	sortFlags(flags)
+
+	return flags
+}
+
+func sortFlags(flags []*Flag) {
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+}
+
+// FlagUsages renders one help line per local flag, aligned in two columns.
+func (c *Command) FlagUsages() string {
+	return flagUsages(c.sortedFlags())
+}
+
+// InheritedFlagUsages renders the persistent flags inherited from the parents.
+func (c *Command) InheritedFlagUsages() string {
+	return flagUsages(c.inheritedFlags())
+}
+
+func flagUsages(flags []*Flag) string {
+	names := make([]string, len(flags))
+	longest := 0
+	for i, f := range flags {
+		name := "    --" + f.Name
+		if f.Short != "" {
+			name = "-" + f.Short + ", --" + f.Name
+		}
+		if f.Type != BoolFlag {
+			name += " " + f.Type.String()
+		}
+
+		names[i] = name
+		if len(name) > longest {
+			longest = len(name)
+		}
+	}
+
+	var sb strings.Builder
+	for i, f := range flags {
+		line := fmt.Sprintf("  %s%s  %s", names[i], strings.Repeat(" ", longest-len(names[i])), f.Description)
+		if f.Required {
+			line += " (required)"
+		} else if f.hasDefault() {
+			line += fmt.Sprintf(" (default: %q)", f.Default)
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HelpText returns the full help of the command: description, usage,
+// subcommands and flags.
+func (c *Command) HelpText() string {
+	description := c.Long
+	if description == "" {
+		description = c.Short
+	}
+
+	if description == "" {
+		return c.Usage()
+	}
+
+	return description + "\n\n" + c.Usage()
+}
+
+// wantsHelp reports whether --help was given before a "--" terminator.
+func wantsHelp(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+
+		if arg == "--help" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// commandTree renders the visible subcommands of c recursively, one
+// command path per line, sorted by name at every level.
+func commandTree(c *Command) string {
+	type row struct {
+		depth int
+		name  string
+		short string
+	}
+
+	rows := []row{}
+	var walk func(cmd *Command, prefix string, depth int)
+	walk = func(cmd *Command, prefix string, depth int) {
+		for _, sub := range cmd.VisibleCommands() {
+			name := strings.TrimSpace(prefix + " " + sub.Name)
+			rows = append(rows, row{depth: depth, name: name, short: sub.Short})
+			walk(sub, name, depth+1)
+		}
+	}
+	walk(c, "", 0)
+
+	longest := 0
+	for _, r := range rows {
+		if width := 2*r.depth + len(r.name); width > longest {
+			longest = width
+		}
+	}
+
+	var sb strings.Builder
+	for _, r := range rows {
+		indent := strings.Repeat("  ", r.depth)
+		padding := longest - len(indent) - len(r.name)
+		sb.WriteString(fmt.Sprintf("  %s%s%s  %s\n", indent, r.name, strings.Repeat(" ", padding), r.short))
+	}
+
+	return sb.String()
+}
+
+// FindCommand resolves a command path such as ["apache", "start"] below c.
+func (c *Command) FindCommand(path []string) (*Command, error) {
+	cmd := c
+	for _, name := range path {
+		sub := cmd.findSubCommand(name)
+		if sub == nil {
+			return nil, unknownCommandError(cmd, name)
+		}
+		cmd = sub
+	}
+
+	return cmd, nil
+}
+
+// AddHelpCommand adds "help [command...]" to cmd. Without arguments it
+// prints every command below cmd; with a command path, e.g.
+// "help apache start", it prints the help of that command.
+func AddHelpCommand(cmd *Command) {
+	helpCmd := NewCommand(
+		"help",
+		"Prints help information",
+		"This command prints help information for a specific command.",
+		func(c *Command, args []string) error {
+			if len(args) == 0 {
+				if cmd.Long != "" {
+					fmt.Println(cmd.Long)
+					fmt.Println()
+				}
+				fmt.Println("Usage:")
+				fmt.Printf("  %s\n", cmd.UseLine())
+				fmt.Println("\nAvailable Commands:")
+				fmt.Print(commandTree(cmd))
+
+				if plugins := cmd.Plugins(); len(plugins) > 0 {
+					longest := 0
+					for _, p := range plugins {
+						longest = max(longest, len(p.Name))
+					}
+
+					fmt.Println("\nPlugins:")
+					for _, p := range plugins {
+						fmt.Printf("  %s%s  %s\n", p.Name, strings.Repeat(" ", longest-len(p.Name)), p.Path)
+					}
+				}
+
+				if flags := cmd.allFlags(); len(flags) > 0 {
+					fmt.Println("\nFlags:")
+					fmt.Print(flagUsages(flags))
+				}
+
+				fmt.Printf("\nUse \"%s help <command>\" for more information about a command.\n", cmd.CommandPath())
+
+				return nil
+			}
+
+			cmdToHelp, err := cmd.FindCommand(args)
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(cmdToHelp.HelpText())
+
+			return nil
+		},
+	)
+	helpCmd.ArgsUsage = "[command...]"
+	helpCmd.ValidArgsFunction = func(c *Command, args []string, toComplete string) []string {
+		target, err := cmd.FindCommand(args)
+		if err != nil {
+			return nil
+		}
+
+		names := []string{}
+		for _, sub := range target.VisibleCommands() {
+			names = append(names, sub.Name)
+		}
+
+		return names
+	}
+	helpCmd.PersistentPreRun = func(c *Command, args []string) error { return nil }
+	cmd.AddCommand(helpCmd)
+}
+
+// AddHelpCommands adds a help command to each of cmds.
+func AddHelpCommands(cmds ...*Command) {
+	for i := 0; i < len(cmds); i++ {
+		AddHelpCommand(cmds[i])
+	}
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Output formats accepted by the --output flag.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// AddOutputFlag adds the persistent --output flag to root. With
+// "--output json" Execute prints the result set by the command, or the
+// error, as a single JSON document on stdout.
+func AddOutputFlag(root *Command) {
+	root.AddFlag("output", "o", OutputText, "Output format: text or json")
+	root.MarkFlagPersistent("output")
+	root.RegisterFlagCompletion("output", func(cmd *Command, args []string, toComplete string) []string {
+		return []string{OutputJSON, OutputText}
+	})
+}
+
+// OutputFormat returns the value of the --output flag, or "text" when the
+// command tree has none.
+func (c *Command) OutputFormat() string {
+	if f := c.findFlag("output"); f != nil {
+		return fmt.Sprint(f.value)
+	}
+
+	return OutputText
+}
+
+// SetResult stores the structured result of the command. It is printed when
+// the output format is json.
+func (c *Command) SetResult(v any) {
+	c.result = v
+}
+
+func validateOutputFormat(c *Command) error {
+	switch format := c.OutputFormat(); format {
+	case OutputText, OutputJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q, expected %q or %q", format, OutputText, OutputJSON)
+	}
+}
+
+type jsonError struct {
+	Code    int    `json:"code"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Usage   string `json:"usage,omitempty"`
+}
+
+type jsonEnvelope struct {
+	OK     bool       `json:"ok"`
+	Result any        `json:"result,omitempty"`
+	Error  *jsonError `json:"error,omitempty"`
+}
+
+func errorKind(code int) string {
+	switch code {
+	case ExitUsage:
+		return "usage"
+	case ExitFlag:
+		return "flag"
+	case ExitUnknownCommand:
+		return "unknown_command"
+	case ExitCancelled:
+		return "cancelled"
+	default:
+		return "failure"
+	}
+}
+
+// writeJSON prints the result of cmd, or err, wrapped in an envelope.
+func writeJSON(w io.Writer, cmd *Command, err error) error {
+	envelope := jsonEnvelope{OK: err == nil, Result: cmd.result}
+
+	if err != nil {
+		envelope.Result = nil
+		envelope.Error = &jsonError{Code: ExitFailure, Kind: errorKind(ExitFailure), Message: err.Error()}
+
+		if cliErr, ok := err.(*Error); ok {
+			envelope.Error.Code = cliErr.Code
+			envelope.Error.Kind = errorKind(cliErr.Code)
+			if cliErr.IsUsage() {
+				envelope.Error.Usage = cliErr.Cmd.UseLine()
+			}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(envelope)
+}
@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// PluginHandler resolves unknown top-level commands to external
+// executables named Prefix+<command>, e.g. "wamp-deploy-fixtures".
+type PluginHandler struct {
+	Prefix string
+	// Dirs returns the directories searched, in order, before PATH.
+	Dirs func() []string
+	// Env returns extra "KEY=value" variables passed to the plugin.
+	Env func() []string
+}
+
+// Plugin is an external command found by a PluginHandler.
+type Plugin struct {
+	Name string
+	Path string
+}
+
+// SetPluginHandler enables external plugin commands on the root command.
+func (c *Command) SetPluginHandler(h *PluginHandler) {
+	c.plugins = h
+}
+
+func (h *PluginHandler) dirs() []string {
+	if h.Dirs == nil {
+		return nil
+	}
+
+	return h.Dirs()
+}
+
+// Find returns the path of the executable implementing name, or "" when there is none.
+func (h *PluginHandler) Find(name string) string {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return ""
+	}
+
+	for _, dir := range h.dirs() {
+		if p, err := exec.LookPath(filepath.Join(dir, h.Prefix+name)); err == nil {
+			return p
+		}
+	}
+
+	if p, err := exec.LookPath(h.Prefix + name); err == nil {
+		return p
+	}
+
+	return ""
+}
+
+// executableExt strips the extension Windows needs to run name and reports
+// whether name looks runnable at all.
+func executableExt(name string, info os.FileInfo) (string, bool) {
+	if runtime.GOOS != "windows" {
+		return name, info.Mode()&0111 != 0
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, known := range []string{".exe", ".bat", ".cmd", ".com"} {
+		if ext == known {
+			return strings.TrimSuffix(name, filepath.Ext(name)), true
+		}
+	}
+
+	return name, false
+}
+
+// Discover lists the plugins found in Dirs and PATH, sorted by name.
+// When several executables share a name the first one found wins, as in Find.
+func (h *PluginHandler) Discover() []Plugin {
+	dirs := append(h.dirs(), filepath.SplitList(os.Getenv("PATH"))...)
+
+	found := map[string]string{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasPrefix(entry.Name(), h.Prefix) {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+
+			name, ok := executableExt(entry.Name(), info)
+			name = strings.TrimPrefix(name, h.Prefix)
+			if !ok || name == "" {
+				continue
+			}
+
+			if _, exists := found[name]; !exists {
+				found[name] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+
+	plugins := make([]Plugin, 0, len(found))
+	for name, p := range found {
+		plugins = append(plugins, Plugin{Name: name, Path: p})
+	}
+
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+
+	return plugins
+}
+
+// Plugins lists the plugins available to c, hiding names taken by built-in commands.
+func (c *Command) Plugins() []Plugin {
+	if c.plugins == nil {
+		return nil
+	}
+
+	plugins := []Plugin{}
+	for _, p := range c.plugins.Discover() {
+		if c.findSubCommand(p.Name) == nil {
+			plugins = append(plugins, p)
+		}
+	}
+
+	return plugins
+}
+
+// pluginCommand returns the first positional word of args, the flags
+// before it and the arguments after it when that word is not a built-in command.
+func (c *Command) pluginCommand(args []string) (string, []string, []string, bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return "", nil, nil, false
+		}
+
+		if len(arg) > 1 && arg[0] == '-' {
+			f := c.lookupFlag(arg)
+			if f != nil && f.Type != BoolFlag && !strings.Contains(arg, "=") {
+				i++ // Skip the flag value
+			}
+			continue
+		}
+
+		if c.findSubCommand(arg) != nil {
+			return "", nil, nil, false
+		}
+
+		return arg, args[:i], args[i+1:], true
+	}
+
+	return "", nil, nil, false
+}
+
+// runPlugin runs the plugin at path with the terminal attached and
+// returns its exit code as a silent *Error.
+func (c *Command) runPlugin(path string, args []string) error {
+	pluginCmd := exec.Command(path, args...)
+	pluginCmd.Stdin = os.Stdin
+	pluginCmd.Stdout = os.Stdout
+	pluginCmd.Stderr = os.Stderr
+	pluginCmd.Env = os.Environ()
+	if c.plugins.Env != nil {
+		pluginCmd.Env = append(pluginCmd.Env, c.plugins.Env()...)
+	}
+
+	err := pluginCmd.Run()
+	if err == nil {
+		return nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &Error{Code: exitErr.ExitCode(), Cmd: c, Err: fmt.Errorf("plugin %s: %w", path, err), silent: true}
+	}
+
+	return &Error{Code: ExitFailure, Cmd: c, Err: fmt.Errorf("plugin %s: %w", path, err)}
+}
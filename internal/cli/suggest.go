@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// suggestionDistance is the largest edit distance still offered as a suggestion.
+const suggestionDistance = 2
+
+// suggest returns the candidates close to typed, best match first.
+func suggest(typed string, candidates []string) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+
+	matches := []match{}
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		if seen[candidate] {
+			continue
+		}
+
+		distance := levenshtein(strings.ToLower(typed), strings.ToLower(candidate))
+		if distance <= suggestionDistance || (len(typed) > 1 && strings.HasPrefix(candidate, typed)) {
+			matches = append(matches, match{name: candidate, distance: distance})
+			seen[candidate] = true
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.name
+	}
+
+	return names
+}
+
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+
+	return "\n\nDid you mean this?\n\t" + strings.Join(suggestions, "\n\t")
+}
+
+// unknownCommandError reports name as an unknown subcommand of c, with suggestions.
+func unknownCommandError(c *Command, name string) *Error {
+	candidates := []string{}
+	for _, sub := range c.VisibleCommands() {
+		candidates = append(candidates, sub.Name)
+		candidates = append(candidates, sub.Aliases...)
+	}
+
+	for _, p := range c.Plugins() {
+		candidates = append(candidates, p.Name)
+	}
+
+	// Suggest the command owning a matching alias rather than the alias itself.
+	suggestions := []string{}
+	for _, s := range suggest(name, candidates) {
+		if sub := c.findSubCommand(s); sub != nil {
+			s = sub.Name
+		}
+
+		if !contains(suggestions, s) {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	return newError(ExitUnknownCommand, c, "unknown command %q for %q%s", name, c.CommandPath(), didYouMean(suggestions))
+}
+
+// unknownFlagError reports arg as an unknown flag of c, with suggestions.
+func unknownFlagError(c *Command, arg string) error {
+	name, _, _ := strings.Cut(arg, "=")
+
+	candidates := []string{}
+	for _, f := range c.allFlags() {
+		candidates = append(candidates, "--"+f.Name)
+	}
+
+	return fmt.Errorf("unknown flag: %s%s", name, didYouMean(suggest(name, candidates)))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
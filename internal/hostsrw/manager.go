@@ -16,16 +16,24 @@ func New(etcDir string) *Manager {
 	}
 }
 
-func (m *Manager) Add(sitename string) error {
+// Exists reports whether sitename has an entry in the hosts file.
+func (m *Manager) Exists(sitename string) (bool, error) {
 	hostsrwExistsCmd := exec.Command(path.Join(m.etcDir, "hostsrw.exe"), "exists", sitename)
 	output, err := hostsrwExistsCmd.Output()
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	str := string(output)
+	return len(output) > 0, nil
+}
 
-	if len(str) > 0 {
+func (m *Manager) Add(sitename string) error {
+	exists, err := m.Exists(sitename)
+	if err != nil {
+		return err
+	}
+
+	if exists {
 		return errors.New("sitename: " + sitename + " already registered on hosts file")
 	}
 
@@ -86,3 +86,10 @@ func (m *Manager) Stop() error {
 
 	return nil
 }
+
+// Running reports whether the instance was started and not stopped since,
+// based on its pid file.
+func (m *Manager) Running() bool {
+	_, err := os.Stat(path.Join(m.tmpDir, m.name+"_pid"))
+	return err == nil
+}
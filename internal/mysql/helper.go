@@ -0,0 +1,34 @@
+package mysql
+
+import (
+	"path"
+
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// DefaultPort is the port MariaDB uses when my.ini does not set one.
+const DefaultPort = "3306"
+
+// SetPort sets the port of the MariaDB in mysqlRoot in its my.ini, keeping
+// the rest of the file as it is.
+func SetPort(mysqlRoot, port string) error {
+	myIniPath := path.Join(mysqlRoot, "my.ini")
+	conf, err := util.LoadConf(myIniPath)
+	if err != nil {
+		return err
+	}
+
+	current, found := conf.GetConf("mysqld", "port")
+	if (found && current == port) || (!found && port == DefaultPort) {
+		return nil
+	}
+
+	conf.SetConf("mysqld", "port", port)
+	if err := conf.SaveConf(myIniPath); err != nil {
+		return err
+	}
+
+	util.PrintLog("INFO").Printf("Set MySQL port to %s in %s\n", port, myIniPath)
+
+	return nil
+}
@@ -82,3 +82,61 @@ func Search(phpPath, keyword string) (string, error) {
 
 	return "", errors.New("unable to find PHP version " + keyword)
 }
+
+// EnableExtensions turns on the extensions in names in the php.ini of the
+// PHP in p, by uncommenting their ;extension= line or adding one when the
+// DLL is in ext. It returns the extensions it enabled; names without a DLL
+// are reported as a warning since not every build ships every extension.
+func EnableExtensions(p string, names []string) ([]string, error) {
+	iniPath := path.Join(p, "php.ini")
+	conf, err := util.LoadConf(iniPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// php.ini keeps its directives in [PHP], a hand-written one may have no
+	// section at all
+	section := ""
+	for _, name := range conf.Sections() {
+		if name == "PHP" {
+			section = name
+		}
+	}
+
+	enabled := []string{}
+	for _, name := range names {
+		isExtension := func(value string) bool {
+			value = strings.Trim(value, `"`)
+			return value == name || value == "php_"+name+".dll"
+		}
+
+		loaded := false
+		for _, value := range conf.GetAllConf(section, "extension") {
+			loaded = loaded || isExtension(value)
+		}
+		if loaded {
+			continue
+		}
+
+		if conf.Uncomment(section, "extension", isExtension) {
+			enabled = append(enabled, name)
+			continue
+		}
+
+		if _, err := os.Stat(path.Join(p, "ext", "php_"+name+".dll")); err != nil {
+			util.PrintLog("WARN").Printf("PHP extension %s is not available in %s\n", name, p)
+			continue
+		}
+
+		conf.AddConf(section, "extension", name)
+		enabled = append(enabled, name)
+	}
+
+	if len(enabled) == 0 {
+		return enabled, nil
+	}
+
+	util.PrintLog("INFO").Printf("Enabled PHP extensions in %s: %s\n", iniPath, strings.Join(enabled, ", "))
+
+	return enabled, conf.SaveConf(iniPath)
+}
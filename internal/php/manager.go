@@ -1,6 +1,7 @@
 package php
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path"
@@ -21,32 +22,38 @@ func New(phpDir, tmpDir string) *Manager {
 	}
 }
 
-func (m *Manager) Install(phpVersion string) error {
-	var err error
-
+// Install downloads and sets up phpVersion. If it fails or ctx is cancelled
+// the partially installed version directory is removed again.
+func (m *Manager) Install(ctx context.Context, phpVersion string) (err error) {
 	if _, err = os.Stat(path.Join(m.phpDir, phpVersion)); err == nil {
 		return errors.New("php installation already exists")
 	}
 
+	defer func() {
+		if err != nil {
+			os.RemoveAll(path.Join(m.phpDir, phpVersion))
+		}
+	}()
+
 	util.PrintLog("INFO").Println("Downloading PHP: " + phpVersion)
-	err = util.DownloadFile(path.Join(m.tmpDir, phpVersion+".zip"), "https://windows.php.net/downloads/releases/archives/"+phpVersion+".zip")
+	err = util.DownloadFile(ctx, path.Join(m.tmpDir, phpVersion+".zip"), "https://windows.php.net/downloads/releases/archives/"+phpVersion+".zip")
 	if err != nil {
 		return err
 	}
 
 	util.PrintLog("INFO").Println("Extracting PHP...")
-	err = util.Unzip(path.Join(m.tmpDir, phpVersion+".zip"), path.Join(m.phpDir, phpVersion))
+	err = util.Unzip(ctx, path.Join(m.tmpDir, phpVersion+".zip"), path.Join(m.phpDir, phpVersion))
 	if err != nil {
 		return err
 	}
 
 	util.PrintLog("INFO").Println("Setup PHP...")
 	phpIni := filepath.Join(m.phpDir, phpVersion)
-	if err := SetPhpIni(phpIni); err != nil {
+	if err = SetPhpIni(phpIni); err != nil {
 		return err
 	}
 
-	if err := SetExtDir(phpIni); err != nil {
+	if err = SetExtDir(phpIni); err != nil {
 		return err
 	}
 
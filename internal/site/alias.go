@@ -0,0 +1,183 @@
+package site
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/apache"
+	"github.com/aziyan99/wamp/internal/hostsrw"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// wildcardPrefix starts an alias matching every subdomain, e.g. *.shop.test.
+const wildcardPrefix = "*."
+
+// NormalizeAlias normalizes alias like NormalizeName. A leading *. is kept
+// for wildcard aliases.
+func NormalizeAlias(alias, tld string) (string, error) {
+	alias = strings.TrimSpace(alias)
+	if !strings.HasPrefix(alias, wildcardPrefix) {
+		return NormalizeName(alias, tld)
+	}
+
+	name, err := NormalizeName(strings.TrimPrefix(alias, wildcardPrefix), tld)
+	if err != nil {
+		return "", err
+	}
+
+	return wildcardPrefix + name, nil
+}
+
+// AddAlias makes the site called sitename answer to alias too: alias is
+// appended to the ServerAlias lines of its vhost, the certificate of an SSL
+// site is reissued with alias as an extra name and alias gets a hosts
+// entry. Wildcard aliases get no hosts entry, the hosts file has none.
+func (m *Manager) AddAlias(sitename, alias string) (*Site, error) {
+	s := m.registry.Get(sitename)
+	if s == nil {
+		return nil, fmt.Errorf("site %s not found", sitename)
+	}
+
+	if err := ValidateName(strings.TrimPrefix(alias, wildcardPrefix)); err != nil {
+		return nil, err
+	}
+
+	// includes the www.<name> every site answers to
+	if owner := m.registry.Lookup(alias); owner != nil {
+		return nil, fmt.Errorf("%s is already served by site %s", alias, owner.Name)
+	}
+
+	if err := m.setAliases(s, append(slices.Clone(s.Aliases), alias)); err != nil {
+		return nil, err
+	}
+
+	m.addHostsEntries([]string{alias})
+
+	return s, nil
+}
+
+// RemoveAlias stops the site called sitename from answering to alias: alias
+// is dropped from the ServerAlias lines of its vhost, the certificate of an
+// SSL site is reissued without it and its hosts entry is removed.
+func (m *Manager) RemoveAlias(sitename, alias string) (*Site, error) {
+	s := m.registry.Get(sitename)
+	if s == nil {
+		return nil, fmt.Errorf("site %s not found", sitename)
+	}
+
+	if !slices.Contains(s.Aliases, alias) {
+		return nil, fmt.Errorf("site %s has no alias %s", sitename, alias)
+	}
+
+	aliases := slices.DeleteFunc(slices.Clone(s.Aliases), func(a string) bool { return a == alias })
+	if err := m.setAliases(s, aliases); err != nil {
+		return nil, err
+	}
+
+	m.removeHostsEntries([]string{alias})
+
+	return s, nil
+}
+
+// setAliases reissues the certificate of s for aliases, rewrites the
+// ServerAlias lines of its vhost and saves the registry. The vhost and
+// certificate are restored to the previous aliases when a step fails.
+func (m *Manager) setAliases(s *Site, aliases []string) error {
+	previous := s.Aliases
+
+	if s.SSL {
+		cert, certKey, err := m.issueCert(s.Name, aliases)
+		if err != nil {
+			return err
+		}
+		s.Cert, s.CertKey = cert, certKey
+	}
+
+	err := rewriteServerAlias(s.Conf, previous, aliases)
+	if err == nil {
+		s.Aliases = aliases
+		if err = m.registry.Save(); err != nil {
+			s.Aliases = previous
+			if vhostErr := rewriteServerAlias(s.Conf, aliases, previous); vhostErr != nil {
+				util.PrintLog("ERROR").Printf("Unable to restore the vhost of %s: %v\n", s.Name, vhostErr)
+			}
+		}
+	}
+
+	if err != nil {
+		if s.SSL {
+			if _, _, certErr := m.issueCert(s.Name, previous); certErr != nil {
+				util.PrintLog("ERROR").Printf("Unable to restore the certificate of %s: %v\n", s.Name, certErr)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// rewriteServerAlias replaces the previous aliases on the ServerAlias lines
+// of the vhost in confPath by aliases, keeping the other names.
+func rewriteServerAlias(confPath string, previous, aliases []string) error {
+	found := false
+	err := apache.RewriteLines(confPath, func(line string) string {
+		trimmedLine := strings.TrimSpace(line)
+		parts := strings.Fields(trimmedLine)
+		if len(parts) == 0 || parts[0] != "ServerAlias" {
+			return line
+		}
+		found = true
+
+		identation := line[:strings.Index(line, trimmedLine)]
+		names := []string{}
+		for _, name := range parts[1:] {
+			if !slices.Contains(previous, name) {
+				names = append(names, name)
+			}
+		}
+		names = append(names, aliases...)
+
+		return identation + "ServerAlias " + strings.Join(names, " ")
+	})
+	if err == nil && !found {
+		err = fmt.Errorf("no ServerAlias line in %s, add 'ServerAlias www.${DOMAIN}' to its vhost", confPath)
+	}
+
+	return err
+}
+
+// addHostsEntries writes the aliases into the hosts file, logging the ones
+// that have to be added by hand.
+func (m *Manager) addHostsEntries(aliases []string) {
+	hostsManager := hostsrw.New(m.etcDir)
+	for _, alias := range aliases {
+		if strings.HasPrefix(alias, wildcardPrefix) {
+			util.PrintLog("INFO").Printf("The windows hosts file does not support wildcards, add the subdomains of '%s' to it manually.\n", alias)
+			continue
+		}
+
+		if err := hostsManager.Add(alias); err != nil {
+			util.PrintLog("INFO").Printf("Unable to write '%s' into windows hosts file. Please add '127.0.0.1 %s' to your windows hosts file manually. Error: %v\n", alias, alias, err)
+		} else {
+			util.PrintLog("INFO").Printf("Wrote '%s' into windows hosts file.\n", alias)
+		}
+	}
+}
+
+// removeHostsEntries removes the aliases from the hosts file, logging the
+// ones that have to be removed by hand.
+func (m *Manager) removeHostsEntries(aliases []string) {
+	hostsManager := hostsrw.New(m.etcDir)
+	for _, alias := range aliases {
+		if strings.HasPrefix(alias, wildcardPrefix) {
+			continue
+		}
+
+		if err := hostsManager.Remove(alias); err != nil {
+			util.PrintLog("INFO").Printf("Unable to remove '%s' into windows hosts file. Please remove '127.0.0.1 %s' from your windows hosts file manually. error: %v\n", alias, alias, err)
+		} else {
+			util.PrintLog("INFO").Printf("Remove '%s' from windows hosts file.\n", alias)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package site
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// CopySites copies the site vhosts and certificates of the Apache in
+// fromA2Root to the one in toA2Root, e.g. when switching Apache versions.
+// Sites the target already has are skipped. The vhosts are copied as they
+// are, so every site keeps its pinned PHP version; only the certificate
+// paths are moved to the target. It returns the names of the copied sites.
+func CopySites(fromA2Root, toA2Root string) ([]string, error) {
+	fromSSLDir := path.Join(fromA2Root, "conf", "sites-ssl")
+	toSSLDir := path.Join(toA2Root, "conf", "sites-ssl")
+
+	for _, dir := range []string{path.Join(toA2Root, "conf", "sites-enabled"), toSSLDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	certs, err := filepath.Glob(path.Join(fromSSLDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cert := range certs {
+		target := path.Join(toSSLDir, filepath.Base(cert))
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+
+		if err := util.CopyFile(cert, target); err != nil {
+			return nil, err
+		}
+	}
+
+	confs, err := filepath.Glob(path.Join(fromA2Root, "conf", "sites-enabled", "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+
+	copied := []string{}
+	for _, conf := range confs {
+		target := path.Join(toA2Root, "conf", "sites-enabled", filepath.Base(conf))
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+
+		content, err := os.ReadFile(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		vhost := strings.ReplaceAll(string(content), util.NormalizePath(fromSSLDir), util.NormalizePath(toSSLDir))
+		if err := os.WriteFile(target, []byte(vhost), 0755); err != nil {
+			return nil, err
+		}
+
+		copied = append(copied, strings.TrimSuffix(filepath.Base(conf), ".conf"))
+	}
+
+	return copied, nil
+}
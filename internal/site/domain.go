@@ -0,0 +1,364 @@
+package site
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Punycode parameters, see RFC 3492 section 5.
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+	acePrefix       = "xn--"
+)
+
+// hstsTLDs are top-level domains on the HSTS preload list of every major
+// browser, so their sites are only reachable over HTTPS.
+var hstsTLDs = map[string]bool{
+	"android": true, "app": true, "bank": true, "boo": true, "channel": true,
+	"chrome": true, "dad": true, "day": true, "dev": true, "eat": true,
+	"esq": true, "fly": true, "foo": true, "gle": true, "gmail": true,
+	"google": true, "hangout": true, "ing": true, "insurance": true,
+	"meme": true, "mov": true, "new": true, "nexus": true, "page": true,
+	"phd": true, "prof": true, "rsvp": true, "search": true, "youtube": true,
+	"zip": true,
+}
+
+// publicTLDs are common top-level domains that resolve on the internet, so
+// a local site would shadow a real one.
+var publicTLDs = map[string]bool{
+	"com": true, "net": true, "org": true, "io": true, "co": true,
+	"info": true, "biz": true, "me": true, "ai": true, "xyz": true,
+	"online": true, "site": true, "tech": true, "store": true, "id": true,
+	"uk": true, "de": true, "us": true, "eu": true, "nl": true,
+}
+
+// NormalizeName turns name into the ASCII host name a site is stored
+// under: it is lowercased, a trailing dot is dropped, "."+tld is appended
+// when it has no dot, and internationalized labels are converted to
+// punycode. The result is checked with ValidateName.
+func NormalizeName(name, tld string) (string, error) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	if name == "" {
+		return "", errors.New("site name is empty")
+	}
+
+	if !strings.Contains(name, ".") {
+		tld = strings.Trim(strings.ToLower(strings.TrimSpace(tld)), ".")
+		if tld == "" {
+			return "", fmt.Errorf("invalid site name %q: it has no domain and site.tld is empty", name)
+		}
+		name += "." + tld
+	}
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		ascii, err := labelToASCII(label)
+		if err != nil {
+			return "", fmt.Errorf("invalid site name %q: %w", name, err)
+		}
+		labels[i] = ascii
+	}
+
+	name = strings.Join(labels, ".")
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// ValidateName checks that name is a lowercase ASCII host name as in
+// RFC 1123 with at least two labels: at most 253 characters, labels of 1 to
+// 63 letters, digits and hyphens not starting or ending with a hyphen, and
+// a top-level domain that is not all digits. Punycode labels must decode.
+func ValidateName(name string) error {
+	if len(name) > 253 {
+		return fmt.Errorf("invalid site name %q: longer than 253 characters", name)
+	}
+
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("invalid site name %q: it must include a domain, e.g. %s.test", name, name)
+	}
+
+	for _, label := range labels {
+		if err := validateLabel(label); err != nil {
+			return fmt.Errorf("invalid site name %q: %w", name, err)
+		}
+	}
+
+	tld := labels[len(labels)-1]
+	if strings.Trim(tld, "0123456789") == "" {
+		return fmt.Errorf("invalid site name %q: the top-level domain is numeric", name)
+	}
+
+	return nil
+}
+
+// validateStoredName checks that name, taken as given because a site may
+// already be stored under it, is safe to use as a file name: it is not
+// empty and holds no path separator or "..". Names of new sites are checked
+// with ValidateName instead.
+func validateStoredName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid site name %q", name)
+	}
+
+	return nil
+}
+
+// TLDWarning returns why the top-level domain of name is a poor choice for
+// a local site, or "" when it is fine.
+func TLDWarning(name string) string {
+	tld := name[strings.LastIndex(name, ".")+1:]
+
+	switch {
+	case hstsTLDs[tld]:
+		return fmt.Sprintf(".%s is on the HSTS preload list, browsers only open %s over HTTPS; use 'site secure' or a .test name", tld, name)
+	case tld == "local":
+		return ".local is reserved for multicast DNS and resolves slowly on many systems, prefer .test"
+	case publicTLDs[tld]:
+		return fmt.Sprintf(".%s is a public TLD, %s hides the real site of that name on this machine; prefer .test", tld, name)
+	}
+
+	return ""
+}
+
+func validateLabel(label string) error {
+	if label == "" {
+		return errors.New("empty label")
+	}
+
+	if len(label) > 63 {
+		return fmt.Errorf("label %q is longer than 63 characters", label)
+	}
+
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return fmt.Errorf("label %q starts or ends with a hyphen", label)
+	}
+
+	for _, c := range label {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return fmt.Errorf("label %q contains %q, only letters, digits and hyphens are allowed", label, c)
+		}
+	}
+
+	if strings.HasPrefix(label, acePrefix) {
+		decoded, err := punyDecode(strings.TrimPrefix(label, acePrefix))
+		if err != nil {
+			return fmt.Errorf("label %q is not valid punycode: %w", label, err)
+		}
+
+		if reencoded, err := labelToASCII(decoded); err != nil || reencoded != label {
+			return fmt.Errorf("label %q is not valid punycode", label)
+		}
+	} else if len(label) >= 4 && label[2:4] == "--" {
+		return fmt.Errorf("label %q has hyphens in the third and fourth position", label)
+	}
+
+	return nil
+}
+
+// labelToASCII returns label unchanged when it is ASCII, otherwise its
+// punycode form with the xn-- prefix.
+func labelToASCII(label string) (string, error) {
+	ascii := true
+	for _, c := range label {
+		if c >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+
+	if ascii {
+		return label, nil
+	}
+
+	encoded, err := punyEncode(label)
+	if err != nil {
+		return "", err
+	}
+
+	return acePrefix + encoded, nil
+}
+
+// punyEncode encodes s with the Punycode algorithm of RFC 3492.
+func punyEncode(s string) (string, error) {
+	input := []rune(s)
+	var output strings.Builder
+
+	for _, c := range input {
+		if c < 0x80 {
+			output.WriteRune(c)
+		}
+	}
+
+	basic := output.Len()
+	handled := basic
+	if basic > 0 {
+		output.WriteByte('-')
+	}
+
+	n, delta, bias := rune(punyInitialN), 0, punyInitialBias
+	for handled < len(input) {
+		m := rune(0x10FFFF)
+		for _, c := range input {
+			if c >= n && c < m {
+				m = c
+			}
+		}
+
+		if int(m-n) > (1<<31-1-delta)/(handled+1) {
+			return "", errors.New("punycode overflow")
+		}
+		delta += int(m-n) * (handled + 1)
+		n = m
+
+		for _, c := range input {
+			if c < n {
+				delta++
+			}
+
+			if c != n {
+				continue
+			}
+
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := punyThreshold(k, bias)
+				if q < t {
+					break
+				}
+				output.WriteByte(punyDigit(t + (q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			output.WriteByte(punyDigit(q))
+
+			bias = punyAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+
+		delta++
+		n++
+	}
+
+	return output.String(), nil
+}
+
+// punyDecode decodes s, the part of a label after xn--, with the Punycode
+// algorithm of RFC 3492.
+func punyDecode(s string) (string, error) {
+	output := []rune{}
+	pos := 0
+	if i := strings.LastIndex(s, "-"); i >= 0 {
+		for _, c := range s[:i] {
+			if c >= 0x80 {
+				return "", errors.New("non-ASCII basic code point")
+			}
+			output = append(output, c)
+		}
+		pos = i + 1
+	}
+
+	n, i, bias := rune(punyInitialN), 0, punyInitialBias
+	for pos < len(s) {
+		oldi, w := i, 1
+		for k := punyBase; ; k += punyBase {
+			if pos >= len(s) {
+				return "", errors.New("truncated input")
+			}
+
+			digit, ok := punyValue(s[pos])
+			if !ok {
+				return "", fmt.Errorf("invalid digit %q", s[pos])
+			}
+			pos++
+
+			if digit > (1<<31-1-i)/w {
+				return "", errors.New("punycode overflow")
+			}
+			i += digit * w
+
+			t := punyThreshold(k, bias)
+			if digit < t {
+				break
+			}
+
+			if w > (1<<31-1)/(punyBase-t) {
+				return "", errors.New("punycode overflow")
+			}
+			w *= punyBase - t
+		}
+
+		bias = punyAdapt(i-oldi, len(output)+1, oldi == 0)
+		n += rune(i / (len(output) + 1))
+		i %= len(output) + 1
+
+		if n > unicode.MaxRune || (n >= 0xD800 && n <= 0xDFFF) {
+			return "", fmt.Errorf("invalid code point U+%X", n)
+		}
+
+		output = append(output[:i], append([]rune{n}, output[i:]...)...)
+		i++
+	}
+
+	return string(output), nil
+}
+
+func punyThreshold(k, bias int) int {
+	switch {
+	case k <= bias:
+		return punyTMin
+	case k >= bias+punyTMax:
+		return punyTMax
+	}
+
+	return k - bias
+}
+
+func punyAdapt(delta, numPoints int, first bool) int {
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+
+	k := 0
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+func punyDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+
+	return byte('0' + d - 26)
+}
+
+func punyValue(c byte) (int, bool) {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A'), true
+	case c >= '0' && c <= '9':
+		return int(c-'0') + 26, true
+	}
+
+	return 0, false
+}
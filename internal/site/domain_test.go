@@ -0,0 +1,141 @@
+package site
+
+import (
+	"strings"
+	"testing"
+)
+
+// punycodeSamples are the sample strings of RFC 3492 section 7.1.
+var punycodeSamples = []struct {
+	name    string
+	unicode string
+	encoded string
+}{
+	{"A Arabic (Egyptian)", "ليهمابتكلموشعربي؟", "egbpdaj6bu4bxfgehfvwxn"},
+	{"B Chinese (simplified)", "他们为什么不说中文", "ihqwcrb4cv8a8dqg056pqjye"},
+	{"C Chinese (traditional)", "他們爲什麽不說中文", "ihqwctvzc91f659drss3x8bo0yb"},
+	{"D Czech", "Pročprostěnemluvíčesky", "Proprostnemluvesky-uyb24dma41a"},
+	{"E Hebrew", "למההםפשוטלאמדבריםעברית", "4dbcagdahymbxekheh6e0a7fei0b"},
+	{"F Hindi (Devanagari)", "यहलोगहिन्दीक्योंनहींबोलसकतेहैं", "i1baa7eci9glrd9b2ae1bj0hfcgg6iyaf8o0a1dig0cd"},
+	{"G Japanese (kanji and hiragana)", "なぜみんな日本語を話してくれないのか", "n8jok5ay5dzabd5bym9f0cm5685rrjetr6pdxa"},
+	{"H Korean (Hangul syllables)", "세계의모든사람들이한국어를이해한다면얼마나좋을까", "989aomsvi5e83db1d2a355cv1e0vak1dwrv93d5xbh15a0dt30a5jpsd879ccm6fea98c"},
+	{"I Russian (Cyrillic)", "почемужеонинеговорятпорусски", "b1abfaaepdrnnbgefbadotcwatmq2g4l"},
+	{"J Spanish", "PorquénopuedensimplementehablarenEspañol", "PorqunopuedensimplementehablarenEspaol-fmd56a"},
+	{"K Vietnamese", "TạisaohọkhôngthểchỉnóitiếngViệt", "TisaohkhngthchnitingVit-kjcr8268qyxafd2f1b9g"},
+	{"L 3<nen>B<gumi><kinpachi><sensei>", "3年B組金八先生", "3B-ww4c5e180e575a65lsy2b"},
+	{"M <amuro><namie>-with-SUPER-MONKEYS", "安室奈美恵-with-SUPER-MONKEYS", "-with-SUPER-MONKEYS-pc58ag80a8qai00g7n9n"},
+	{"N Hello-Another-Way-<sorezore><no><basho>", "Hello-Another-Way-それぞれの場所", "Hello-Another-Way--fc4qua05auwb3674vfr0b"},
+	{"O <hitotsu><yane><no><shita>2", "ひとつ屋根の下2", "2-u9tlzr9756bt3uc0v"},
+	{"P Maji<de>Koi<suru>5<byou><mae>", "MajiでKoiする5秒前", "MajiKoi5-783gue6qz075azm5e"},
+	{"Q <pafii>de<runba>", "パフィーdeルンバ", "de-jg4avhby1noc0d"},
+	{"R <sono><supiido><de>", "そのスピードで", "d9juau41awczczp"},
+	{"S -> $1.00 <-", "-> $1.00 <-", "-> $1.00 <--"},
+}
+
+func TestPunyEncode(t *testing.T) {
+	for _, sample := range punycodeSamples {
+		encoded, err := punyEncode(sample.unicode)
+		if err != nil {
+			t.Errorf("%s: punyEncode: %v", sample.name, err)
+			continue
+		}
+
+		if encoded != sample.encoded {
+			t.Errorf("%s: punyEncode = %q, want %q", sample.name, encoded, sample.encoded)
+		}
+	}
+}
+
+func TestPunyDecode(t *testing.T) {
+	for _, sample := range punycodeSamples {
+		decoded, err := punyDecode(sample.encoded)
+		if err != nil {
+			t.Errorf("%s: punyDecode: %v", sample.name, err)
+			continue
+		}
+
+		if decoded != sample.unicode {
+			t.Errorf("%s: punyDecode = %q, want %q", sample.name, decoded, sample.unicode)
+		}
+	}
+
+	// the last code point
+	if decoded, err := punyDecode("dn32g"); err != nil || decoded != "\U0010FFFF" {
+		t.Errorf("punyDecode(dn32g) = %q, %v, want U+10FFFF", decoded, err)
+	}
+
+	// digits are case-insensitive
+	if decoded, err := punyDecode("CAF-DMA"); err != nil || decoded != "CAFé" {
+		t.Errorf("punyDecode(CAF-DMA) = %q, %v, want CAFé", decoded, err)
+	}
+}
+
+func TestPunyRoundTrip(t *testing.T) {
+	for _, label := range []string{"café", "bücher", "ñandú", "日本語", "παράδειγμα", "пример", "aébéc", strings.Repeat("é", 40), "\U0001F600smile"} {
+		encoded, err := punyEncode(label)
+		if err != nil {
+			t.Errorf("punyEncode(%q): %v", label, err)
+			continue
+		}
+
+		decoded, err := punyDecode(encoded)
+		if err != nil {
+			t.Errorf("punyDecode(%q) of %q: %v", encoded, label, err)
+			continue
+		}
+
+		if decoded != label {
+			t.Errorf("round trip of %q = %q via %q", label, decoded, encoded)
+		}
+	}
+}
+
+func TestPunyDecodeInvalid(t *testing.T) {
+	for _, encoded := range []string{
+		// overflows the 32 bit state of RFC 3492 section 6.4
+		"99999999999999",
+		"a9999999999999999999",
+		// decodes to U+110000, beyond the last code point
+		"en32g",
+		// decodes to U+D800, a surrogate
+		"ib9b",
+		// truncated, i.e. ends on a digit that asks for more
+		"9",
+		"abc-9",
+		// invalid digits and non-ASCII basic code points
+		"a_b",
+		"é-dma",
+	} {
+		if decoded, err := punyDecode(encoded); err == nil {
+			t.Errorf("punyDecode(%q) = %q, want an error", encoded, decoded)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	for _, tt := range []struct {
+		name, tld, want string
+	}{
+		{"Shop", "test", "shop.test"},
+		{"café.test", "test", "xn--caf-dma.test"},
+		{"Café", "test", "xn--caf-dma.test"},
+		{"api.shop.test.", "test", "api.shop.test"},
+		{"bücher.de", "test", "xn--bcher-kva.de"},
+	} {
+		got, err := NormalizeName(tt.name, tt.tld)
+		if err != nil {
+			t.Errorf("NormalizeName(%q, %q): %v", tt.name, tt.tld, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("NormalizeName(%q, %q) = %q, want %q", tt.name, tt.tld, got, tt.want)
+		}
+	}
+
+	for _, name := range []string{"", "my_app.test", "-shop.test", "shop.123", "xn--zz.test", "ab--cd.test", strings.Repeat("a", 64) + ".test"} {
+		if got, err := NormalizeName(name, "test"); err == nil {
+			t.Errorf("NormalizeName(%q) = %q, want an error", name, got)
+		}
+	}
+}
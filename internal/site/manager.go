@@ -1,49 +1,199 @@
 package site
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
-	"os/exec"
 	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/aziyan99/wamp/internal/hostsrw"
+	"github.com/aziyan99/wamp/internal/php"
 	"github.com/aziyan99/wamp/internal/util"
 )
 
+// Site describes a site as recorded in the Registry. Name is its domain,
+// Aliases the other domains it answers to, and Type its project type, see
+// ProjectTypes, if it was scaffolded with one. Redirect sends the HTTP
+// requests of an SSL site to HTTPS. Linked is set for sites served from a
+// directory outside www, whose directory is never deleted; Parked is the
+// parked directory the site was generated from, see Park.
+type Site struct {
+	Name       string            `json:"name"`
+	Aliases    []string          `json:"aliases,omitempty"`
+	Dir        string            `json:"dir"`
+	DocRoot    string            `json:"doc_root"`
+	Type       string            `json:"type,omitempty"`
+	Conf       string            `json:"conf"`
+	PHPVersion string            `json:"php_version"`
+	PHPDir     string            `json:"php_dir"`
+	SSL        bool              `json:"ssl"`
+	Cert       string            `json:"cert,omitempty"`
+	CertKey    string            `json:"cert_key,omitempty"`
+	Redirect   bool              `json:"https_redirect,omitempty"`
+	Linked     bool              `json:"linked,omitempty"`
+	Parked     string            `json:"parked,omitempty"`
+	Created    time.Time         `json:"created"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
 type Manager struct {
+	registry        *Registry
 	wwwDir          string
 	activeApacheDir string
 	selectedPHPDir  string
 	etcDir          string
+	tmpDir          string
+	trashDir        string
 }
 
-func New(wwwDir, activeApacheDir, selectedPHPDir, etcDir string) *Manager {
+func New(registry *Registry, wwwDir, activeApacheDir, selectedPHPDir, etcDir, tmpDir, trashDir string) *Manager {
 	return &Manager{
+		registry:        registry,
 		wwwDir:          wwwDir,
 		activeApacheDir: activeApacheDir,
 		selectedPHPDir:  selectedPHPDir,
 		etcDir:          etcDir,
+		tmpDir:          tmpDir,
+		trashDir:        trashDir,
 	}
 }
 
-func (m *Manager) Add(sitename string, sslEnable bool) error {
+// Add creates the site sitename in www. With a projectType, see
+// ProjectTypes, the PHP extensions of the type are enabled and an empty site
+// directory is bootstrapped, e.g. with composer create-project; on failure
+// the directory is removed again if Add created it.
+func (m *Manager) Add(ctx context.Context, sitename, projectType string, sslEnable bool) (site *Site, err error) {
 
-	// TODO: Validate sitename must include domain
-	// TODO: Handle site with 'public' path
-	// TODO: Accept project type (e.g., laravel, wordpress, moodle)
+	var project ProjectType
+	if projectType != "" {
+		if project, err = LookupProjectType(projectType); err != nil {
+			return nil, err
+		}
+	}
 
 	siteDir := path.Join(m.wwwDir, sitename)
 	siteConf := path.Join(m.activeApacheDir, "conf", "sites-enabled", sitename+".conf")
 
 	isDirSiteExists, err := util.DirExists(siteDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	_, err = os.Stat(siteConf)
-	if err == nil && isDirSiteExists {
-		return errors.New("site exists")
+	if (err == nil && isDirSiteExists) || m.registry.Conflict(sitename) != nil {
+		return nil, errors.New("site exists")
+	}
+
+	if err := m.checkNew(sitename, sslEnable); err != nil {
+		return nil, err
+	}
+
+	if !isDirSiteExists {
+		if err = os.Mkdir(siteDir, 0755); err != nil {
+			return nil, errors.New("unable to create site dir")
+		}
+
+		defer func() {
+			if err != nil {
+				os.RemoveAll(siteDir)
+			}
+		}()
+	}
+
+	docRoot := DetectDocRoot(siteDir)
+	if projectType != "" {
+		if docRoot, err = m.scaffold(ctx, project, siteDir); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.create(sitename, siteDir, docRoot, projectType, false, sslEnable)
+}
+
+// scaffold enables the PHP extensions of project, bootstraps siteDir when
+// it is empty and writes the default .htaccess. It returns the docroot.
+func (m *Manager) scaffold(ctx context.Context, project ProjectType, siteDir string) (string, error) {
+	if _, err := php.EnableExtensions(m.selectedPHPDir, project.Extensions); err != nil {
+		return "", fmt.Errorf("unable to enable PHP extensions: %w", err)
+	}
+
+	entries, err := os.ReadDir(siteDir)
+	if err != nil {
+		return "", err
+	}
+
+	if project.Bootstrap != nil && len(entries) == 0 {
+		util.PrintLog("INFO").Printf("Bootstrapping %s site...\n", project.Name)
+		err := project.Bootstrap(ctx, Bootstrap{
+			Dir:      siteDir,
+			PHPDir:   m.selectedPHPDir,
+			EtcDir:   m.etcDir,
+			CacheDir: path.Join(m.tmpDir, "cache"),
+		})
+		if err != nil {
+			return "", err
+		}
+	} else if project.Bootstrap != nil {
+		util.PrintLog("INFO").Printf("%s is not empty, skipping the %s bootstrap\n", siteDir, project.Name)
+	}
+
+	docRoot := path.Join(siteDir, project.DocRoot)
+	if err := os.MkdirAll(docRoot, 0755); err != nil {
+		return "", err
+	}
+
+	if project.HTAccess != "" {
+		htaccess := path.Join(docRoot, ".htaccess")
+		if _, err := os.Stat(htaccess); errors.Is(err, fs.ErrNotExist) {
+			if err := os.WriteFile(htaccess, []byte(project.HTAccess), 0644); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return docRoot, nil
+}
+
+// Link serves the existing directory dir, which may live anywhere, as
+// sitename. The directory is recorded as linked so Remove leaves it alone.
+func (m *Manager) Link(sitename, dir string, sslEnable bool) (*Site, error) {
+	siteDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	siteDir = util.NormalizePath(siteDir)
+
+	info, err := os.Stat(siteDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, errors.New(siteDir + " is not a directory")
+	}
+
+	siteConf := path.Join(m.activeApacheDir, "conf", "sites-enabled", sitename+".conf")
+	if _, err := os.Stat(siteConf); err == nil || m.registry.Conflict(sitename) != nil {
+		return nil, errors.New("site exists")
+	}
+
+	if err := m.checkNew(sitename, sslEnable); err != nil {
+		return nil, err
+	}
+
+	return m.create(sitename, siteDir, DetectDocRoot(siteDir), "", true, sslEnable)
+}
+
+// checkNew checks that sitename is a valid host name, that the selected
+// PHP is installed and that no certificate is left over for sitename.
+func (m *Manager) checkNew(sitename string, sslEnable bool) error {
+	if err := ValidateName(sitename); err != nil {
+		return err
 	}
 
 	isPHPExists, err := util.DirExists(m.selectedPHPDir)
@@ -67,44 +217,55 @@ func (m *Manager) Add(sitename string, sslEnable bool) error {
 		}
 	}
 
-	if !isDirSiteExists {
-		if err = os.Mkdir(siteDir, 0755); err != nil {
-			return errors.New("unable to create site dir")
-		}
-	} else {
-		_, err = os.Stat(path.Join(siteDir, "public", "index.php"))
-		if err != nil && !errors.Is(err, fs.ErrNotExist) {
-			_, err = os.Stat(path.Join(siteDir, "public", "index.html"))
-			if err != nil && !errors.Is(err, fs.ErrNotExist) {
-				// do nothing
-			} else {
-				siteDir = path.Join(siteDir, "public")
-			}
-		} else {
-			siteDir = path.Join(siteDir, "public")
-		}
-	}
+	return nil
+}
+
+// create writes the vhost, certificate, registry entry and hosts entry of
+// the site in siteDir. The certificate and vhost are removed again when a
+// later step fails, so checkNew does not refuse the next attempt.
+func (m *Manager) create(sitename, siteDir, docRoot, projectType string, linked, sslEnable bool) (created *Site, err error) {
+	siteConf := path.Join(m.activeApacheDir, "conf", "sites-enabled", sitename+".conf")
 
-	confFileValue := []byte(SiteVHostStub(siteDir, sitename, m.selectedPHPDir))
+	site := &Site{
+		Name:       sitename,
+		Dir:        siteDir,
+		DocRoot:    docRoot,
+		Type:       projectType,
+		Conf:       siteConf,
+		PHPVersion: path.Base(m.selectedPHPDir),
+		PHPDir:     m.selectedPHPDir,
+		SSL:        sslEnable,
+		Linked:     linked,
+		Created:    time.Now().UTC().Truncate(time.Second),
+	}
 
 	if sslEnable {
-		err = os.Chdir(path.Join(m.activeApacheDir, "conf", "sites-ssl"))
-		if err != nil {
-			return err
+		if site.Cert, site.CertKey, err = m.issueCert(sitename, nil); err != nil {
+			return nil, err
 		}
 
-		mkCertCmd := exec.Command(path.Join(m.etcDir, "mkcert.exe"), sitename)
-		mkCertCmd.Stdout = os.Stdout
-		err = mkCertCmd.Run()
-		if err != nil {
-			return errors.New("unable to create site ssl conf")
-		}
+		defer func() {
+			if err == nil {
+				return
+			}
 
-		confFileValue = []byte(SiteVHostSSLStub(siteDir, sitename, m.selectedPHPDir, path.Join(m.activeApacheDir, "conf", "sites-ssl", sitename+".pem"), path.Join(m.activeApacheDir, "conf", "sites-ssl", sitename+"-key.pem")))
+			if certErr := removeCert(site); certErr != nil {
+				util.PrintLog("ERROR").Printf("Unable to remove the certificate of %s: %v\n", sitename, certErr)
+			}
+		}()
 	}
 
-	if err = os.WriteFile(siteConf, confFileValue, 0755); err != nil {
-		return err
+	if err = m.writeVHost(site); err != nil {
+		return nil, err
+	}
+
+	m.registry.Put(site)
+	if err = m.registry.Save(); err != nil {
+		m.registry.Delete(sitename)
+		if confErr := os.Remove(siteConf); confErr != nil {
+			util.PrintLog("ERROR").Printf("Unable to remove %s: %v\n", siteConf, confErr)
+		}
+		return nil, err
 	}
 
 	hostsManager := hostsrw.New(m.etcDir)
@@ -115,52 +276,97 @@ func (m *Manager) Add(sitename string, sslEnable bool) error {
 		util.PrintLog("INFO").Printf("Wrote '%s' into windows hosts file.\n", sitename)
 	}
 
-	return nil
+	return site, nil
 }
 
-func (m *Manager) Remove(sitename string) error {
-	siteDir := path.Join(m.wwwDir, sitename)
-	siteConf := path.Join(m.activeApacheDir, "conf", "sites-enabled", sitename+".conf")
-	accessLog := path.Join(m.activeApacheDir, "logs", sitename+"-access.log")
-	errLog := path.Join(m.activeApacheDir, "logs", sitename+"-error.log")
-	siteSSLConf := path.Join(m.activeApacheDir, "conf", "sites-ssl", sitename+".pem")
-	siteSSLKeyConf := path.Join(m.activeApacheDir, "conf", "sites-ssl", sitename+"-key.pem")
+// ExistingName returns the name the existing site called name is stored
+// under. name is taken as is, lowercased, when a site of the registry, the
+// trash or sites-enabled has it, so sites created before names got a
+// domain, e.g. myapp, stay reachable; otherwise it is normalized like a new
+// name, so shop finds shop.test.
+func (m *Manager) ExistingName(name, tld string) (string, error) {
+	lowered := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	if validateStoredName(lowered) == nil {
+		if m.registry.Get(lowered) != nil {
+			return lowered, nil
+		}
 
-	if err := os.RemoveAll(siteDir); err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return err
-	}
+		if _, err := os.Stat(path.Join(m.activeApacheDir, "conf", "sites-enabled", lowered+".conf")); err == nil {
+			return lowered, nil
+		}
 
-	if err := os.Remove(siteConf); err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return err
+		if entries, err := m.TrashedSites(); err == nil {
+			for _, entry := range entries {
+				if entry.Site.Name == lowered {
+					return lowered, nil
+				}
+			}
+		}
 	}
 
-	if err := os.Remove(accessLog); err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return err
-	}
+	return NormalizeName(name, tld)
+}
 
-	if err := os.Remove(errLog); err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return err
+// Remove detaches the site called sitename: its vhost, certificate and logs
+// are moved into a trash entry, see Trashed, and its hosts entry and
+// registry entry are removed. The site files stay where they are unless
+// purge is set, in which case they are moved into the trash entry too.
+// Restore brings the site back.
+func (m *Manager) Remove(sitename string, purge bool) (*Trashed, error) {
+	// the name becomes a path in sites-enabled; sites created before names
+	// got a domain are called e.g. myapp, so only path safety is checked
+	if err := validateStoredName(sitename); err != nil {
+		return nil, err
 	}
 
-	if _, err := os.Stat(siteSSLConf); err == nil {
-		if err := os.Remove(siteSSLConf); err != nil && !errors.Is(err, fs.ErrNotExist) {
-			return err
+	s := m.registry.Get(sitename)
+	if s == nil {
+		// sites created before the registry existed
+		conf := path.Join(m.activeApacheDir, "conf", "sites-enabled", sitename+".conf")
+		parsed, err := ParseVHost(conf)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("site %s not found", sitename)
+			}
+			return nil, err
+		}
+
+		if parsed.Name != sitename {
+			return nil, fmt.Errorf("%s defines DOMAIN %q, not %s", conf, parsed.Name, sitename)
 		}
+		s = parsed
+	}
+
+	entry, err := m.trash(s, purge)
+	if err != nil {
+		return nil, err
 	}
 
-	if _, err := os.Stat(siteSSLKeyConf); err == nil {
-		if err := os.Remove(siteSSLKeyConf); err != nil && !errors.Is(err, fs.ErrNotExist) {
-			return err
+	if m.registry.Delete(sitename) {
+		if err := m.registry.Save(); err != nil {
+			return nil, err
 		}
 	}
 
 	hostsManager := hostsrw.New(m.etcDir)
-	err := hostsManager.Remove(sitename)
+	err = hostsManager.Remove(sitename)
 	if err != nil {
 		util.PrintLog("INFO").Printf("Unable to remove '%s' into windows hosts file. Please remove '127.0.0.1 %s' from your windows hosts file manually. error: %v\n", sitename, sitename, err)
 	} else {
 		util.PrintLog("INFO").Printf("Remove '%s' from windows hosts file.\n", sitename)
 	}
+	m.removeHostsEntries(s.Aliases)
 
-	return nil
+	return entry, nil
+}
+
+// inWWW reports whether dir is a directory inside the www dir.
+func (m *Manager) inWWW(dir string) bool {
+	rel, err := filepath.Rel(util.NormalizePath(m.wwwDir), util.NormalizePath(dir))
+	if err != nil {
+		return false
+	}
+
+	rel = util.NormalizePath(rel)
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, "../")
 }
@@ -0,0 +1,174 @@
+package site
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// Park is a directory whose immediate subfolders are each served as
+// <folder>.<tld>. The sites are generated by SyncPark.
+type Park struct {
+	Dir string `json:"dir"`
+	// PHP is the version keyword of the sites, empty for php.default.
+	PHP string `json:"php,omitempty"`
+	SSL bool   `json:"ssl,omitempty"`
+}
+
+// SyncResult lists the sites SyncPark added and removed and the folders it
+// skipped.
+type SyncResult struct {
+	Dir     string   `json:"dir"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Skipped []string `json:"skipped"`
+}
+
+// ParkDir returns dir as an absolute, normalized path, the key of its Park.
+func ParkDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	return util.NormalizePath(abs), nil
+}
+
+// ParkedSiteName returns the site name of the parked folder, or false when
+// the folder name is not a valid host label, see NormalizeName.
+func ParkedSiteName(folder, tld string) (string, bool) {
+	if strings.Contains(folder, ".") {
+		return "", false
+	}
+
+	name, err := NormalizeName(folder, tld)
+	if err != nil {
+		return "", false
+	}
+
+	return name, true
+}
+
+// SyncPark makes the sites of p match its subfolders: every new folder is
+// linked as <folder>.<tld> with the PHP in phpDir, and the sites of folders
+// that are gone are removed. Sites added with site add or site link keep
+// their name; a folder clashing with one is skipped.
+func (m *Manager) SyncPark(p *Park, phpDir, tld string) (*SyncResult, error) {
+	result := &SyncResult{Dir: p.Dir, Added: []string{}, Removed: []string{}, Skipped: []string{}}
+
+	folders, err := util.SubDirs(p.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	parkManager := *m
+	parkManager.selectedPHPDir = phpDir
+
+	wanted := map[string]bool{}
+	for _, folder := range folders {
+		if strings.HasPrefix(folder, ".") {
+			continue
+		}
+
+		sitename, ok := ParkedSiteName(folder, tld)
+		if !ok {
+			util.PrintLog("WARN").Printf("Skipping %s, %q is not a valid host name\n", path.Join(p.Dir, folder), folder)
+			result.Skipped = append(result.Skipped, folder)
+			continue
+		}
+		wanted[sitename] = true
+
+		if existing := m.registry.Conflict(sitename); existing != nil {
+			if existing.Parked != p.Dir {
+				util.PrintLog("WARN").Printf("Skipping %s, site %s already exists\n", path.Join(p.Dir, folder), sitename)
+				result.Skipped = append(result.Skipped, folder)
+			}
+			continue
+		}
+
+		s, err := parkManager.Link(sitename, path.Join(p.Dir, folder), p.SSL)
+		if err != nil {
+			return result, err
+		}
+
+		s.Parked = p.Dir
+		if err := m.registry.Save(); err != nil {
+			return result, err
+		}
+
+		util.PrintLog("INFO").Printf("Site '%s' serves %s\n", sitename, s.DocRoot)
+		result.Added = append(result.Added, sitename)
+	}
+
+	for _, s := range m.registry.List() {
+		if s.Parked != p.Dir || wanted[s.Name] {
+			continue
+		}
+
+		if _, err := os.Stat(s.Dir); err == nil {
+			continue
+		}
+
+		if err := m.detach(s); err != nil {
+			return result, err
+		}
+
+		util.PrintLog("INFO").Printf("Site '%s' removed, %s is gone\n", s.Name, s.Dir)
+		result.Removed = append(result.Removed, s.Name)
+	}
+
+	return result, nil
+}
+
+// Unpark removes the sites generated for p, see detach. The folders are
+// kept.
+func (m *Manager) Unpark(p *Park) ([]string, error) {
+	removed := []string{}
+	for _, s := range m.registry.List() {
+		if s.Parked != p.Dir {
+			continue
+		}
+
+		if err := m.detach(s); err != nil {
+			return removed, err
+		}
+		removed = append(removed, s.Name)
+	}
+
+	return removed, nil
+}
+
+// detach deletes the vhost, certificate, logs, registry entry and hosts
+// entries of the parked site s. Parked sites are generated from their
+// folder, so unlike Remove nothing is kept in the trash.
+func (m *Manager) detach(s *Site) error {
+	if err := removeCert(s); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.Conf); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// Apache may still hold the logs open
+	for _, name := range []string{s.Name + "-access.log", s.Name + "-error.log"} {
+		if err := os.Remove(path.Join(m.activeApacheDir, "logs", name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			util.PrintLog("WARN").Printf("Unable to delete %s: %v\n", name, err)
+		}
+	}
+
+	if m.registry.Delete(s.Name) {
+		if err := m.registry.Save(); err != nil {
+			return err
+		}
+	}
+
+	m.removeHostsEntries(append([]string{s.Name}, s.Aliases...))
+
+	return nil
+}
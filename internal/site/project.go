@@ -0,0 +1,183 @@
+package site
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// Bootstrap holds what a ProjectType needs to fill a new site.
+type Bootstrap struct {
+	// Dir is the empty site directory.
+	Dir    string
+	PHPDir string
+	EtcDir string
+	// CacheDir keeps downloaded archives for the next site of the type.
+	CacheDir string
+}
+
+// ProjectType describes a kind of site site add can scaffold.
+type ProjectType struct {
+	Name string
+	// DocRoot is the docroot relative to the site directory, "" for the
+	// directory itself.
+	DocRoot string
+	// Extensions are the PHP extensions the project needs, enabled in the
+	// php.ini of the site PHP.
+	Extensions []string
+	// HTAccess is written to the docroot when it has no .htaccess yet.
+	HTAccess string
+	// Bootstrap fills the site directory, nil to leave it empty.
+	Bootstrap func(ctx context.Context, b Bootstrap) error
+}
+
+const wordpressHTAccess = `# BEGIN WordPress
+<IfModule mod_rewrite.c>
+RewriteEngine On
+RewriteRule .* - [E=HTTP_AUTHORIZATION:%{HTTP:Authorization}]
+RewriteBase /
+RewriteRule ^index\.php$ - [L]
+RewriteCond %{REQUEST_FILENAME} !-f
+RewriteCond %{REQUEST_FILENAME} !-d
+RewriteRule . /index.php [L]
+</IfModule>
+# END WordPress
+`
+
+const staticIndex = `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>%s</title>
+</head>
+<body>
+    <h1>%s</h1>
+</body>
+</html>
+`
+
+// ProjectTypes are the types site add --type accepts.
+var ProjectTypes = []ProjectType{
+	{
+		Name:       "laravel",
+		DocRoot:    "public",
+		Extensions: []string{"curl", "fileinfo", "mbstring", "openssl", "pdo_mysql", "zip"},
+		Bootstrap: func(ctx context.Context, b Bootstrap) error {
+			return composerCreateProject(ctx, b, "laravel/laravel")
+		},
+	},
+	{
+		Name:       "wordpress",
+		Extensions: []string{"curl", "exif", "fileinfo", "gd", "intl", "mbstring", "mysqli", "openssl", "zip"},
+		HTAccess:   wordpressHTAccess,
+		Bootstrap: func(ctx context.Context, b Bootstrap) error {
+			return unpackArchive(ctx, b, "wordpress-latest.zip", "https://wordpress.org/latest.zip", "wordpress")
+		},
+	},
+	{
+		Name:       "moodle",
+		Extensions: []string{"curl", "exif", "fileinfo", "gd", "intl", "mbstring", "mysqli", "openssl", "soap", "sodium", "zip"},
+		Bootstrap: func(ctx context.Context, b Bootstrap) error {
+			return unpackArchive(ctx, b, "moodle-latest-405.zip", "https://download.moodle.org/download.php/direct/stable405/moodle-latest-405.zip", "moodle")
+		},
+	},
+	{
+		Name: "static",
+		Bootstrap: func(ctx context.Context, b Bootstrap) error {
+			name := path.Base(b.Dir)
+			return os.WriteFile(path.Join(b.Dir, "index.html"), []byte(fmt.Sprintf(staticIndex, name, name)), 0644)
+		},
+	},
+}
+
+// LookupProjectType returns the project type called name.
+func LookupProjectType(name string) (ProjectType, error) {
+	for _, t := range ProjectTypes {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+
+	return ProjectType{}, fmt.Errorf("unknown project type %q, expected one of: %s", name, strings.Join(ProjectTypeNames(), ", "))
+}
+
+// ProjectTypeNames returns the names of every project type, sorted.
+func ProjectTypeNames() []string {
+	names := make([]string, 0, len(ProjectTypes))
+	for _, t := range ProjectTypes {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// composerCreateProject runs composer create-project for pkg into the site
+// directory with the site PHP.
+func composerCreateProject(ctx context.Context, b Bootstrap, pkg string) error {
+	composer := path.Join(b.EtcDir, "composer.phar")
+	if _, err := os.Stat(composer); err != nil {
+		return errors.New("composer.phar not found in " + b.EtcDir + ", run 'wamp install'")
+	}
+
+	util.PrintLog("INFO").Printf("Running composer create-project %s...\n", pkg)
+	composerCmd := exec.CommandContext(ctx, path.Join(b.PHPDir, "php.exe"), composer, "create-project", "--no-interaction", "--prefer-dist", pkg, b.Dir)
+	composerCmd.Stdout = util.LogOutput()
+	composerCmd.Stderr = util.LogOutput()
+	if err := composerCmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("composer create-project %s failed: %w", pkg, err)
+	}
+
+	return nil
+}
+
+// unpackArchive extracts the zip at url into the site directory, dropping
+// its top folder. The archive is downloaded once into the cache as name.
+func unpackArchive(ctx context.Context, b Bootstrap, name, url, topFolder string) error {
+	if err := os.MkdirAll(b.CacheDir, 0755); err != nil {
+		return err
+	}
+
+	archive := path.Join(b.CacheDir, name)
+	if _, err := os.Stat(archive); err != nil {
+		util.PrintLog("INFO").Printf("Downloading %s...\n", url)
+		if err := util.DownloadFile(ctx, archive, url); err != nil {
+			return err
+		}
+	} else {
+		util.PrintLog("INFO").Printf("Using cached %s\n", archive)
+	}
+
+	// extract next to the site so moving the files never crosses drives
+	unpackDir := path.Join(path.Dir(b.Dir), "."+path.Base(b.Dir)+".unpack")
+	defer os.RemoveAll(unpackDir)
+
+	util.PrintLog("INFO").Printf("Extracting %s...\n", name)
+	if err := util.Unzip(ctx, archive, unpackDir); err != nil {
+		return err
+	}
+
+	srcDir := path.Join(unpackDir, topFolder)
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := os.Rename(path.Join(srcDir, entry.Name()), path.Join(b.Dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
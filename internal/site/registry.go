@@ -0,0 +1,236 @@
+package site
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// Registry is the list of sites wamp manages, stored as JSON (sites.json in
+// the wamp dir). It is the source of truth for the site commands; the
+// vhosts, certificates and hosts entries are derived from it.
+type Registry struct {
+	path  string
+	sites map[string]*Site
+	parks map[string]*Park
+	mu    sync.RWMutex
+}
+
+type registryFile struct {
+	Sites []*Site `json:"sites"`
+	Parks []*Park `json:"parks,omitempty"`
+}
+
+// LoadRegistry reads the registry at path. A missing file is an empty registry.
+func LoadRegistry(path string) (*Registry, error) {
+	r := &Registry{path: path, sites: map[string]*Site{}, parks: map[string]*Park{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return r, nil
+		}
+		return nil, err
+	}
+
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid site registry %s: %w", path, err)
+	}
+
+	for _, s := range file.Sites {
+		r.sites[s.Name] = s
+	}
+
+	for _, p := range file.Parks {
+		r.parks[p.Dir] = p
+	}
+
+	return r, nil
+}
+
+// Path returns the file the registry is stored in.
+func (r *Registry) Path() string {
+	return r.path
+}
+
+// Get returns the site called name, or nil.
+func (r *Registry) Get(name string) *Site {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sites[name]
+}
+
+// Lookup returns the site answering to domain, or nil: the site of that
+// name, the site whose implicit www.<name> alias it is, the site having it
+// as an alias or, failing those, the site with the longest wildcard alias
+// covering it, e.g. *.shop.test for a.shop.test.
+func (r *Registry) Lookup(domain string) *Site {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if s, ok := r.sites[domain]; ok {
+		return s
+	}
+
+	if s, ok := r.sites[strings.TrimPrefix(domain, "www.")]; ok {
+		return s
+	}
+
+	var wildcard *Site
+	longest := 0
+	for _, s := range r.sites {
+		for _, alias := range s.Aliases {
+			if alias == domain {
+				return s
+			}
+
+			// Apache matches * across dots, so *.shop.test covers a.b.shop.test
+			parent, ok := strings.CutPrefix(alias, wildcardPrefix)
+			if ok && strings.HasSuffix(domain, "."+parent) && len(parent) > longest {
+				wildcard, longest = s, len(parent)
+			}
+		}
+	}
+
+	return wildcard
+}
+
+// Conflict returns the site already answering to name or to www.<name>, the
+// alias every vhost serves, or nil. A new site called name must have none.
+func (r *Registry) Conflict(name string) *Site {
+	if s := r.Lookup(name); s != nil {
+		return s
+	}
+
+	return r.Lookup("www." + name)
+}
+
+// List returns every site, ordered by name.
+func (r *Registry) List() []*Site {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sites := make([]*Site, 0, len(r.sites))
+	for _, s := range r.sites {
+		sites = append(sites, s)
+	}
+
+	sort.Slice(sites, func(i, j int) bool {
+		return sites[i].Name < sites[j].Name
+	})
+
+	return sites
+}
+
+// Names returns the names of every site, sorted.
+func (r *Registry) Names() []string {
+	names := []string{}
+	for _, s := range r.List() {
+		names = append(names, s.Name)
+	}
+
+	return names
+}
+
+// Put adds s or replaces the site with the same name.
+func (r *Registry) Put(s *Site) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sites[s.Name] = s
+}
+
+// Delete removes the site called name and reports whether it existed.
+func (r *Registry) Delete(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.sites[name]
+	delete(r.sites, name)
+
+	return ok
+}
+
+// Parks returns every parked directory, ordered by path.
+func (r *Registry) Parks() []*Park {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	parks := make([]*Park, 0, len(r.parks))
+	for _, p := range r.parks {
+		parks = append(parks, p)
+	}
+
+	sort.Slice(parks, func(i, j int) bool {
+		return parks[i].Dir < parks[j].Dir
+	})
+
+	return parks
+}
+
+// GetPark returns the park of dir, or nil.
+func (r *Registry) GetPark(dir string) *Park {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.parks[dir]
+}
+
+// PutPark adds p or replaces the park of the same directory.
+func (r *Registry) PutPark(p *Park) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.parks[p.Dir] = p
+}
+
+// DeletePark removes the park of dir and reports whether it existed.
+func (r *Registry) DeletePark(dir string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.parks[dir]
+	delete(r.parks, dir)
+
+	return ok
+}
+
+// Save writes the registry. The file is replaced at once so an interrupted
+// write never leaves half a registry behind.
+func (r *Registry) Save() error {
+	data, err := json.MarshalIndent(registryFile{Sites: r.List(), Parks: r.Parks()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(r.path+".tmp", append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(r.path+".tmp", r.path)
+}
+
+// MoveApache points the conf and certificate paths of every site from the
+// Apache in fromA2Root to the one in toA2Root, see CopySites.
+func (r *Registry) MoveApache(fromA2Root, toA2Root string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.sites {
+		for _, p := range []*string{&s.Conf, &s.Cert, &s.CertKey} {
+			from := util.NormalizePath(fromA2Root)
+			if normalized := util.NormalizePath(*p); strings.HasPrefix(normalized, from+"/") {
+				*p = toA2Root + strings.TrimPrefix(normalized, from)
+			}
+		}
+	}
+}
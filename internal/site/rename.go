@@ -0,0 +1,177 @@
+package site
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/apache"
+	"github.com/aziyan99/wamp/internal/hostsrw"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// Rename moves the site called oldName to newName: its directory when it is
+// www/<oldName>, the DOMAIN and ROOT of its vhost, its certificate, its logs,
+// its registry entry and its hosts entry. Every step is undone when a later
+// one fails, so the site is either fully renamed or left as it was.
+func (m *Manager) Rename(oldName, newName string) (renamed *Site, err error) {
+	s := m.registry.Get(oldName)
+	if s == nil {
+		return nil, fmt.Errorf("site %s not found", oldName)
+	}
+
+	if s.Parked != "" {
+		return nil, fmt.Errorf("site %s is served from the parked directory %s, rename its folder and run 'wamp park sync' instead", oldName, s.Parked)
+	}
+
+	if err := ValidateName(newName); err != nil {
+		return nil, err
+	}
+
+	newConf := path.Join(path.Dir(s.Conf), newName+".conf")
+	if _, err := os.Stat(newConf); err == nil || m.registry.Conflict(newName) != nil {
+		return nil, errors.New("site " + newName + " exists")
+	}
+
+	// undo holds the steps taken so far, run in reverse when a step fails
+	undo := []func() error{}
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				util.PrintLog("ERROR").Printf("Unable to roll back rename of %s: %v\n", oldName, undoErr)
+			}
+		}
+	}()
+
+	updated := *s
+	updated.Name = newName
+	updated.Conf = newConf
+
+	if !s.Linked && m.inWWW(s.Dir) && path.Base(util.NormalizePath(s.Dir)) == oldName {
+		updated.Dir = path.Join(path.Dir(util.NormalizePath(s.Dir)), newName)
+		if _, err := os.Stat(updated.Dir); err == nil {
+			return nil, errors.New("site dir " + updated.Dir + " exists")
+		}
+
+		if err := os.Rename(s.Dir, updated.Dir); err != nil {
+			return nil, err
+		}
+		undo = append(undo, func() error { return os.Rename(updated.Dir, s.Dir) })
+
+		updated.DocRoot = updated.Dir + strings.TrimPrefix(util.NormalizePath(s.DocRoot), util.NormalizePath(s.Dir))
+	}
+
+	if s.SSL {
+		if updated.Cert, updated.CertKey, err = m.issueCert(newName, s.Aliases); err != nil {
+			return nil, err
+		}
+		undo = append(undo, func() error { return removeCert(&updated) })
+	}
+
+	for _, suffix := range []string{"-access.log", "-error.log"} {
+		oldLog := path.Join(m.activeApacheDir, "logs", oldName+suffix)
+		newLog := path.Join(m.activeApacheDir, "logs", newName+suffix)
+		if _, err := os.Stat(oldLog); err != nil {
+			continue
+		}
+
+		if err := os.Rename(oldLog, newLog); err != nil {
+			return nil, err
+		}
+		undo = append(undo, func() error { return os.Rename(newLog, oldLog) })
+	}
+
+	oldVHost, err := os.ReadFile(s.Conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := util.CopyFile(s.Conf, newConf); err != nil {
+		return nil, err
+	}
+	undo = append(undo, func() error { return os.Remove(newConf) })
+
+	if err := apache.RewriteLines(newConf, renameVHostLine(s, &updated)); err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(s.Conf); err != nil {
+		return nil, err
+	}
+	undo = append(undo, func() error { return os.WriteFile(s.Conf, oldVHost, 0755) })
+
+	m.registry.Delete(oldName)
+	m.registry.Put(&updated)
+	undo = append(undo, func() error {
+		m.registry.Delete(newName)
+		m.registry.Put(s)
+		return m.registry.Save()
+	})
+	if err := m.registry.Save(); err != nil {
+		return nil, err
+	}
+
+	if err := removeCert(s); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		util.PrintLog("WARN").Printf("Unable to delete the certificate of %s: %v\n", oldName, err)
+	}
+
+	hostsManager := hostsrw.New(m.etcDir)
+	if err := hostsManager.Remove(oldName); err != nil {
+		util.PrintLog("INFO").Printf("Unable to remove '%s' into windows hosts file. Please remove '127.0.0.1 %s' from your windows hosts file manually. error: %v\n", oldName, oldName, err)
+	}
+	if err := hostsManager.Add(newName); err != nil {
+		util.PrintLog("INFO").Printf("Unable to write '%s' into windows hosts file. Please add '127.0.0.1 %s' to your windows hosts file manually. Error: %v\n", newName, newName, err)
+	} else {
+		util.PrintLog("INFO").Printf("Wrote '%s' into windows hosts file.\n", newName)
+	}
+
+	return &updated, nil
+}
+
+// renameVHostLine rewrites the lines of the vhost of from that name its
+// domain, docroot or certificate for to.
+func renameVHostLine(from, to *Site) func(line string) string {
+	return func(line string) string {
+		trimmedLine := strings.TrimSpace(line)
+		parts := strings.Fields(trimmedLine)
+		if len(parts) < 2 {
+			return line
+		}
+
+		identation := line[:strings.Index(line, trimmedLine)]
+
+		switch {
+		case len(parts) >= 3 && strings.EqualFold(parts[0], "define") && parts[1] == "DOMAIN":
+			return fmt.Sprintf("%sdefine DOMAIN \"%s\"", identation, to.Name)
+		case len(parts) >= 3 && strings.EqualFold(parts[0], "define") && parts[1] == "ROOT":
+			return fmt.Sprintf("%sdefine ROOT \"%s\"", identation, util.NormalizePath(to.DocRoot))
+		case parts[0] == "SSLCertificateFile" && to.Cert != "":
+			return fmt.Sprintf("%sSSLCertificateFile \"%s\"", identation, util.NormalizePath(to.Cert))
+		case parts[0] == "SSLCertificateKeyFile" && to.CertKey != "":
+			return fmt.Sprintf("%sSSLCertificateKeyFile \"%s\"", identation, util.NormalizePath(to.CertKey))
+		case parts[0] == "ServerName" || parts[0] == "ServerAlias":
+			// literal names of hand-edited vhosts
+			changed := false
+			for i, name := range parts[1:] {
+				switch name {
+				case from.Name:
+					parts[i+1], changed = to.Name, true
+				case "www." + from.Name:
+					parts[i+1], changed = "www."+to.Name, true
+				}
+			}
+			if changed {
+				return identation + strings.Join(parts, " ")
+			}
+		}
+
+		return line
+	}
+}
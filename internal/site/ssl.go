@@ -0,0 +1,136 @@
+package site
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// issueCert creates the mkcert certificate of sitename in the sites-ssl dir
+// of the active Apache, with its aliases as extra names, and returns the
+// certificate and key paths. An existing certificate is replaced.
+func (m *Manager) issueCert(sitename string, aliases []string) (string, string, error) {
+	sslDir := path.Join(m.activeApacheDir, "conf", "sites-ssl")
+	if err := os.Chdir(sslDir); err != nil {
+		return "", "", err
+	}
+
+	// mkcert names the files after the first name and the number of
+	// the others, so the paths are given explicitly
+	args := []string{"-cert-file", sitename + ".pem", "-key-file", sitename + "-key.pem", sitename}
+	args = append(args, aliases...)
+
+	mkCertCmd := exec.Command(path.Join(m.etcDir, "mkcert.exe"), args...)
+	mkCertCmd.Stdout = util.LogOutput()
+	if err := mkCertCmd.Run(); err != nil {
+		return "", "", errors.New("unable to create site ssl conf")
+	}
+
+	return path.Join(sslDir, sitename+".pem"), path.Join(sslDir, sitename+"-key.pem"), nil
+}
+
+// removeCert deletes the certificate and key of s.
+func removeCert(s *Site) error {
+	for _, file := range []string{s.Cert, s.CertKey} {
+		if file == "" {
+			continue
+		}
+
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeVHost writes the vhost of s from its registry entry.
+func (m *Manager) writeVHost(s *Site) error {
+	vhost := SiteVHostStub(s.DocRoot, s.Name, s.PHPDir)
+	switch {
+	case s.SSL && s.Redirect:
+		vhost = SiteVHostSSLRedirectStub(s.DocRoot, s.Name, s.PHPDir, s.Cert, s.CertKey)
+	case s.SSL:
+		vhost = SiteVHostSSLStub(s.DocRoot, s.Name, s.PHPDir, s.Cert, s.CertKey)
+	}
+
+	if len(s.Aliases) > 0 {
+		vhost = strings.ReplaceAll(vhost, "ServerAlias www.${DOMAIN}", "ServerAlias www.${DOMAIN} "+strings.Join(s.Aliases, " "))
+	}
+
+	return os.WriteFile(s.Conf, []byte(vhost), 0755)
+}
+
+// Secure serves the site called sitename over HTTPS: a certificate is
+// issued when the site has none and the vhost is regenerated with the *:443
+// block. With redirect the HTTP vhost only redirects to HTTPS. The site
+// files and hosts entry are left alone.
+func (m *Manager) Secure(sitename string, redirect bool) (*Site, error) {
+	s := m.registry.Get(sitename)
+	if s == nil {
+		return nil, fmt.Errorf("site %s not found", sitename)
+	}
+
+	issue := !s.SSL
+	for _, file := range []string{s.Cert, s.CertKey} {
+		if _, err := os.Stat(file); err != nil {
+			issue = true
+		}
+	}
+
+	if issue {
+		cert, certKey, err := m.issueCert(sitename, s.Aliases)
+		if err != nil {
+			return nil, err
+		}
+		s.Cert, s.CertKey = cert, certKey
+	}
+
+	s.SSL = true
+	s.Redirect = redirect
+	if err := m.writeVHost(s); err != nil {
+		return nil, err
+	}
+
+	if err := m.registry.Save(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Unsecure serves the site called sitename over HTTP only: the vhost is
+// regenerated without the *:443 block and the certificate is deleted. The
+// site files and hosts entry are left alone.
+func (m *Manager) Unsecure(sitename string) (*Site, error) {
+	s := m.registry.Get(sitename)
+	if s == nil {
+		return nil, fmt.Errorf("site %s not found", sitename)
+	}
+
+	if !s.SSL {
+		return nil, fmt.Errorf("site %s does not use SSL", sitename)
+	}
+
+	cert := *s
+	s.SSL, s.Redirect, s.Cert, s.CertKey = false, false, "", ""
+	if err := m.writeVHost(s); err != nil {
+		return nil, err
+	}
+
+	if err := m.registry.Save(); err != nil {
+		return nil, err
+	}
+
+	if err := removeCert(&cert); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,154 @@
+package site
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/aziyan99/wamp/internal/hostsrw"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// VHost states reported by Status.
+const (
+	VHostOK      = "ok"
+	VHostMissing = "missing"
+	VHostInvalid = "invalid"
+)
+
+// Status is the health of a registered site, as shown by site list.
+type Status struct {
+	*Site
+	// DetectedDocRoot is the docroot site add would pick for Dir today.
+	DetectedDocRoot string     `json:"detected_doc_root"`
+	CertExpires     *time.Time `json:"cert_expires,omitempty"`
+	// Hosts is nil when the hosts file could not be checked.
+	Hosts    *bool    `json:"hosts"`
+	VHost    string   `json:"vhost"`
+	Problems []string `json:"problems"`
+}
+
+// Healthy reports whether no problem was found.
+func (s Status) Healthy() bool {
+	return len(s.Problems) == 0
+}
+
+// DetectDocRoot returns the docroot for the site in siteDir: its public
+// folder when that holds an index.php or index.html, siteDir otherwise.
+func DetectDocRoot(siteDir string) string {
+	for _, index := range []string{"index.php", "index.html"} {
+		if _, err := os.Stat(path.Join(siteDir, "public", index)); err == nil {
+			return path.Join(siteDir, "public")
+		}
+	}
+
+	return siteDir
+}
+
+// Status checks the vhost, certificate and hosts entry of s.
+func (m *Manager) Status(s *Site) Status {
+	status := Status{Site: s, DetectedDocRoot: DetectDocRoot(s.Dir), Problems: []string{}}
+
+	status.VHost, status.Problems = checkVHost(s)
+
+	if util.NormalizePath(status.DetectedDocRoot) != util.NormalizePath(s.DocRoot) {
+		status.Problems = append(status.Problems, fmt.Sprintf("docroot is %s but %s was detected", s.DocRoot, status.DetectedDocRoot))
+	}
+
+	if s.SSL {
+		expires, err := CertExpiry(s.Cert)
+		if err != nil {
+			status.Problems = append(status.Problems, fmt.Sprintf("certificate: %v", err))
+		} else {
+			status.CertExpires = &expires
+			if time.Now().After(expires) {
+				status.Problems = append(status.Problems, "certificate expired")
+			}
+		}
+	}
+
+	exists, err := hostsrw.New(m.etcDir).Exists(s.Name)
+	if err != nil {
+		status.Problems = append(status.Problems, fmt.Sprintf("unable to check hosts file: %v", err))
+	} else {
+		status.Hosts = &exists
+		if !exists {
+			status.Problems = append(status.Problems, "no hosts entry")
+		}
+	}
+
+	return status
+}
+
+// checkVHost compares the vhost of s with the registry and checks the paths
+// it points to.
+func checkVHost(s *Site) (string, []string) {
+	problems := []string{}
+
+	vhost, err := ParseVHost(s.Conf)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return VHostMissing, append(problems, "vhost "+s.Conf+" is missing")
+		}
+		return VHostInvalid, append(problems, fmt.Sprintf("vhost: %v", err))
+	}
+
+	if vhost.Name != s.Name {
+		problems = append(problems, fmt.Sprintf("vhost DOMAIN is %q", vhost.Name))
+	}
+
+	if util.NormalizePath(vhost.DocRoot) != util.NormalizePath(s.DocRoot) {
+		problems = append(problems, fmt.Sprintf("vhost ROOT is %q", vhost.DocRoot))
+	}
+
+	if vhost.SSL != s.SSL {
+		problems = append(problems, fmt.Sprintf("vhost SSL is %t", vhost.SSL))
+	}
+
+	if _, err := os.Stat(vhost.DocRoot); err != nil {
+		problems = append(problems, "docroot "+vhost.DocRoot+" does not exist")
+	}
+
+	if _, err := os.Stat(path.Join(vhost.PHPDir, "php-cgi.exe")); err != nil {
+		problems = append(problems, "php-cgi.exe not found in "+vhost.PHPDir)
+	}
+
+	for _, cert := range []string{vhost.Cert, vhost.CertKey} {
+		if cert == "" {
+			continue
+		}
+		if _, err := os.Stat(cert); err != nil {
+			problems = append(problems, "certificate file "+cert+" does not exist")
+		}
+	}
+
+	if len(problems) > 0 {
+		return VHostInvalid, problems
+	}
+
+	return VHostOK, problems
+}
+
+// CertExpiry returns when the PEM certificate in certPath expires.
+func CertExpiry(certPath string) (time.Time, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, fmt.Errorf("no certificate in %s", certPath)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return cert.NotAfter, nil
+}
@@ -0,0 +1,352 @@
+package site
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aziyan99/wamp/internal/hostsrw"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// Trashed is a removed site kept in the trash dir so Restore can bring it
+// back. Each entry is a directory holding trashed.json and the vhost
+// (conf), certificate (ssl) and logs of the site, plus its files (files)
+// when it was purged.
+type Trashed struct {
+	ID      string    `json:"id"`
+	Site    *Site     `json:"site"`
+	Removed time.Time `json:"removed"`
+	Purged  bool      `json:"purged"`
+	Dir     string    `json:"-"`
+}
+
+// TrashedSites returns the entries of the trash dir, most recent first.
+func (m *Manager) TrashedSites() ([]*Trashed, error) {
+	ids, err := util.SubDirs(m.trashDir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*Trashed{}
+	for _, id := range ids {
+		data, err := os.ReadFile(path.Join(m.trashDir, id, "trashed.json"))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		entry := &Trashed{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			return nil, fmt.Errorf("invalid trash entry %s: %w", id, err)
+		}
+		entry.Dir = path.Join(m.trashDir, id)
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Removed.Equal(entries[j].Removed) {
+			return entries[i].Removed.After(entries[j].Removed)
+		}
+		// entries removed within the same second get a -2, -3... suffix
+		if len(entries[i].ID) != len(entries[j].ID) {
+			return len(entries[i].ID) > len(entries[j].ID)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+
+	return entries, nil
+}
+
+// EmptyTrash deletes every entry of the trash dir for good, including the
+// files of purged sites, and returns the deleted entries.
+func (m *Manager) EmptyTrash() ([]*Trashed, error) {
+	entries, err := m.TrashedSites()
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := []*Trashed{}
+	for _, entry := range entries {
+		if err := os.RemoveAll(entry.Dir); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, entry)
+	}
+
+	return deleted, nil
+}
+
+// trash moves the vhost, certificate and logs of s, and its files when
+// purge is set, into a new trash entry. The manifest is written first and
+// the moves are undone when one fails; an entry whose undo failed too stays
+// in the trash so its files can still be found.
+func (m *Manager) trash(s *Site, purge bool) (trashed *Trashed, err error) {
+	removed := time.Now().UTC().Truncate(time.Second)
+	entry := &Trashed{
+		ID:      s.Name + "-" + removed.Format("20060102T150405Z"),
+		Site:    s,
+		Removed: removed,
+	}
+	entry.Dir = path.Join(m.trashDir, entry.ID)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(entry.Dir); err != nil {
+			break
+		}
+		entry.ID = fmt.Sprintf("%s-%s-%d", s.Name, removed.Format("20060102T150405Z"), i)
+		entry.Dir = path.Join(m.trashDir, entry.ID)
+	}
+
+	moves := [][2]string{
+		{s.Conf, path.Join(entry.Dir, "conf", s.Name+".conf")},
+	}
+	if s.Cert != "" {
+		moves = append(moves, [2]string{s.Cert, path.Join(entry.Dir, "ssl", filepath.Base(s.Cert))})
+	}
+	if s.CertKey != "" {
+		moves = append(moves, [2]string{s.CertKey, path.Join(entry.Dir, "ssl", filepath.Base(s.CertKey))})
+	}
+	for _, name := range []string{s.Name + "-access.log", s.Name + "-error.log"} {
+		moves = append(moves, [2]string{path.Join(m.activeApacheDir, "logs", name), path.Join(entry.Dir, "logs", name)})
+	}
+
+	// Only directories wamp created under www are purged, never the source
+	// tree of a linked site.
+	if purge && (s.Linked || !m.inWWW(s.Dir)) {
+		util.PrintLog("WARN").Printf("Keeping site dir %s, it is not inside %s\n", s.Dir, m.wwwDir)
+	} else if purge {
+		moves = append(moves, [2]string{s.Dir, path.Join(entry.Dir, "files")})
+		entry.Purged = true
+	}
+
+	if err := os.MkdirAll(entry.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	if err := writeManifest(entry); err != nil {
+		os.RemoveAll(entry.Dir)
+		return nil, err
+	}
+
+	undo := []func() error{}
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		undone := true
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				util.PrintLog("ERROR").Printf("Unable to roll back removal of %s: %v\n", s.Name, undoErr)
+				undone = false
+			}
+		}
+
+		if undone {
+			os.RemoveAll(entry.Dir)
+		} else {
+			util.PrintLog("ERROR").Printf("Files of %s left in %s\n", s.Name, entry.Dir)
+		}
+	}()
+
+	for _, move := range moves {
+		src, dst := move[0], move[1]
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+
+		if err := moveBack(&undo, src, dst); err != nil {
+			return nil, err
+		}
+	}
+
+	return entry, nil
+}
+
+// moveBack moves src to dst and pushes the step moving it back onto undo.
+// A directory that was copied but whose src could not be removed completely
+// can not be moved back; its undo step fails so the leftovers are reported.
+func moveBack(undo *[]func() error, src, dst string) error {
+	err := util.Move(src, dst)
+	switch {
+	case errors.Is(err, util.ErrSourceKept):
+		util.PrintLog("WARN").Printf("%v\n", err)
+		*undo = append(*undo, func() error {
+			return fmt.Errorf("%s can not be moved back, parts of it are left in %s", dst, src)
+		})
+	case err != nil:
+		return err
+	default:
+		*undo = append(*undo, func() error { return util.Move(dst, src) })
+	}
+
+	return nil
+}
+
+// writeManifest writes trashed.json of entry.
+func writeManifest(entry *Trashed) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path.Join(entry.Dir, "trashed.json"), append(data, '\n'), 0644)
+}
+
+// Restore brings back the most recently removed site called sitename from
+// the trash, attached to the active Apache. Its files are moved back when
+// the site was purged. Every step is undone when a later one fails, so the
+// entry stays in the trash.
+func (m *Manager) Restore(sitename string) (restored *Site, err error) {
+	// the name of a trashed site may predate ValidateName, e.g. myapp
+	if err := validateStoredName(sitename); err != nil {
+		return nil, err
+	}
+
+	if m.registry.Conflict(sitename) != nil {
+		return nil, errors.New("site exists")
+	}
+
+	entries, err := m.TrashedSites()
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *Trashed
+	for _, e := range entries {
+		if e.Site.Name == sitename {
+			entry = e
+			break
+		}
+	}
+
+	if entry == nil {
+		return nil, fmt.Errorf("site %s not found in %s", sitename, m.trashDir)
+	}
+
+	// work on a copy so a failed restore leaves the entry as it was
+	s := *entry.Site
+	for _, alias := range s.Aliases {
+		if owner := m.registry.Lookup(alias); owner != nil {
+			return nil, fmt.Errorf("alias %s of site %s is now served by site %s", alias, sitename, owner.Name)
+		}
+	}
+
+	conf := path.Join(m.activeApacheDir, "conf", "sites-enabled", sitename+".conf")
+	if _, err := os.Stat(conf); err == nil {
+		return nil, errors.New("site vhost " + conf + " exists")
+	}
+
+	if entry.Purged {
+		if _, err := os.Stat(s.Dir); err == nil {
+			return nil, errors.New("site dir " + s.Dir + " exists")
+		}
+	}
+
+	vhost, err := os.ReadFile(path.Join(entry.Dir, "conf", sitename+".conf"))
+	if err != nil {
+		return nil, err
+	}
+
+	undo := []func() error{}
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				util.PrintLog("ERROR").Printf("Unable to roll back restore of %s: %v\n", sitename, undoErr)
+			}
+		}
+	}()
+
+	if entry.Purged {
+		if err := moveBack(&undo, path.Join(entry.Dir, "files"), s.Dir); err != nil {
+			return nil, err
+		}
+	}
+
+	sslDir := path.Join(m.activeApacheDir, "conf", "sites-ssl")
+	oldSSLDir := ""
+	if s.Cert != "" {
+		oldSSLDir = filepath.Dir(s.Cert)
+	}
+
+	for _, file := range []*string{&s.Cert, &s.CertKey} {
+		if *file == "" {
+			continue
+		}
+
+		src := path.Join(entry.Dir, "ssl", filepath.Base(*file))
+		*file = path.Join(sslDir, filepath.Base(*file))
+		if err := moveBack(&undo, src, *file); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range []string{sitename + "-access.log", sitename + "-error.log"} {
+		src := path.Join(entry.Dir, "logs", name)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+
+		// a log written since the removal is kept, the trashed one goes
+		// next to it
+		dst := path.Join(m.activeApacheDir, "logs", name)
+		if _, err := os.Stat(dst); err == nil {
+			dst = path.Join(m.activeApacheDir, "logs", strings.TrimSuffix(name, ".log")+"-"+entry.Removed.Format("20060102T150405Z")+".log")
+		}
+
+		if err := moveBack(&undo, src, dst); err != nil {
+			return nil, err
+		}
+	}
+
+	// the site may have been removed from another Apache version
+	if oldSSLDir != "" {
+		vhost = []byte(strings.ReplaceAll(string(vhost), util.NormalizePath(oldSSLDir), util.NormalizePath(sslDir)))
+	}
+
+	s.Conf = conf
+	if err := os.WriteFile(conf, vhost, 0755); err != nil {
+		return nil, err
+	}
+	undo = append(undo, func() error { return os.Remove(conf) })
+
+	m.registry.Put(&s)
+	undo = append(undo, func() error {
+		m.registry.Delete(sitename)
+		return m.registry.Save()
+	})
+	if err := m.registry.Save(); err != nil {
+		return nil, err
+	}
+
+	// without its manifest the entry is no longer listed, even when the
+	// rest of it can not be deleted
+	if err := os.Remove(path.Join(entry.Dir, "trashed.json")); err != nil {
+		util.PrintLog("WARN").Printf("Unable to delete trash entry %s: %v\n", entry.Dir, err)
+	} else if err := os.RemoveAll(entry.Dir); err != nil {
+		util.PrintLog("WARN").Printf("Unable to delete trash entry %s: %v\n", entry.Dir, err)
+	}
+
+	hostsManager := hostsrw.New(m.etcDir)
+	if err := hostsManager.Add(sitename); err != nil {
+		util.PrintLog("INFO").Printf("Unable to write '%s' into windows hosts file. Please add '127.0.0.1 %s' to your windows hosts file manually. Error: %v\n", sitename, sitename, err)
+	} else {
+		util.PrintLog("INFO").Printf("Wrote '%s' into windows hosts file.\n", sitename)
+	}
+	m.addHostsEntries(s.Aliases)
+
+	return &s, nil
+}
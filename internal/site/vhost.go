@@ -0,0 +1,117 @@
+package site
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/apache"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// ParseVHost reads the settings of a vhost written by SiteVHostStub or
+// SiteVHostSSLStub back into a Site. The site directory is the docroot, or
+// its parent when the docroot is a public folder.
+func ParseVHost(confPath string) (*Site, error) {
+	file, err := os.Open(confPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	site := &Site{Conf: confPath}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		parts := strings.Fields(strings.TrimSpace(scanner.Text()))
+
+		switch {
+		case len(parts) >= 3 && strings.EqualFold(parts[0], "define"):
+			value := strings.Trim(strings.Join(parts[2:], " "), `"`)
+			switch parts[1] {
+			case "ROOT":
+				site.DocRoot = value
+			case "DOMAIN":
+				site.Name = value
+			case "PHPRC_PATH":
+				site.PHPDir = value
+				site.PHPVersion = path.Base(value)
+			}
+		case len(parts) >= 2 && parts[0] == "SSLCertificateFile":
+			site.SSL = true
+			site.Cert = strings.Trim(strings.Join(parts[1:], " "), `"`)
+		case len(parts) >= 2 && parts[0] == "SSLCertificateKeyFile":
+			site.CertKey = strings.Trim(strings.Join(parts[1:], " "), `"`)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	site.Dir = site.DocRoot
+	if path.Base(site.DocRoot) == "public" {
+		site.Dir = path.Dir(site.DocRoot)
+	}
+
+	return site, nil
+}
+
+// SetPHP switches the site called sitename to the PHP in phpDir. Only the
+// PHPRC_PATH define and any FcgidWrapper or FcgidInitialEnv PHPRC line
+// holding a literal PHP path are rewritten; the rest of the vhost is kept
+// as it is. It returns the site as it was before.
+func (m *Manager) SetPHP(sitename, phpDir string) (*Site, error) {
+	registered := m.registry.Get(sitename)
+	if registered == nil {
+		return nil, fmt.Errorf("site %s not found", sitename)
+	}
+
+	if _, err := os.Stat(path.Join(phpDir, "php-cgi.exe")); err != nil {
+		return nil, errors.New("php-cgi.exe not found in " + phpDir)
+	}
+
+	old := *registered
+	newPath := util.NormalizePath(phpDir)
+
+	err := apache.RewriteLines(registered.Conf, func(line string) string {
+		trimmedLine := strings.TrimSpace(line)
+		parts := strings.Fields(trimmedLine)
+		identation := line[:strings.Index(line, trimmedLine)]
+
+		switch {
+		case len(parts) >= 3 && strings.EqualFold(parts[0], "define") && parts[1] == "PHPRC_PATH":
+			return fmt.Sprintf("%sdefine PHPRC_PATH \"%s\"", identation, newPath)
+		case len(parts) >= 3 && parts[0] == "FcgidInitialEnv" && parts[1] == "PHPRC" && !strings.Contains(parts[2], "${"):
+			return fmt.Sprintf("%sFcgidInitialEnv PHPRC \"%s\"", identation, newPath)
+		case len(parts) >= 2 && parts[0] == "FcgidWrapper" && !strings.Contains(parts[1], "${"):
+			// keep what follows the wrapper path, e.g. " .php"
+			rest := strings.TrimSpace(strings.TrimPrefix(trimmedLine, "FcgidWrapper"))
+			suffix := ""
+			if strings.HasPrefix(rest, `"`) {
+				if i := strings.Index(rest[1:], `"`); i >= 0 {
+					suffix = rest[i+2:]
+				}
+			} else if i := strings.Index(rest, " "); i >= 0 {
+				suffix = rest[i:]
+			}
+			return fmt.Sprintf("%sFcgidWrapper \"%s/php-cgi.exe\"%s", identation, newPath, suffix)
+		}
+
+		return line
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	registered.PHPDir = phpDir
+	registered.PHPVersion = path.Base(phpDir)
+	if err := m.registry.Save(); err != nil {
+		return nil, err
+	}
+
+	return &old, nil
+}
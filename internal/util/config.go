@@ -2,27 +2,52 @@ package util
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
 )
 
-// INI represents the structure of an INI file.
-// It uses a map of sections, where each section is a map of key-value pairs.
+// INI is an editable INI document. It keeps every line of the source,
+// comments and blank lines included, in order, and only rewrites the lines
+// that were changed, so hand edits survive a load/save round trip.
+//
+// Keys before the first section header belong to the global section "".
+// Duplicate keys are kept; GetConf and SetConf act on the last occurrence,
+// the one PHP and MariaDB honour.
 type INI struct {
-	data map[string]map[string]string
-	mu   sync.RWMutex // For thread-safe access
+	lines   []*iniLine
+	newline string
+	mu      sync.RWMutex // For thread-safe access
 }
 
-// NewINI creates and initializes a new INI structure.
+type iniLineKind int
+
+const (
+	iniOther iniLineKind = iota // blank lines, comments and anything unparsable
+	iniSection
+	iniKey
+)
+
+type iniLine struct {
+	raw     string
+	kind    iniLineKind
+	section string
+	key     string
+	value   string
+	// raw[valueAt:valueEnd] is the value, raw[valueEnd:] a trailing comment
+	valueAt  int
+	valueEnd int
+}
+
+// NewINI creates an empty INI document.
 func NewINI() *INI {
-	return &INI{
-		data: make(map[string]map[string]string),
-	}
+	return &INI{newline: "\n"}
 }
 
-// LoadFile parses an INI file from the given path.
+// LoadConf parses the INI file at filename.
 func LoadConf(filename string) (*INI, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -30,102 +55,311 @@ func LoadConf(filename string) (*INI, error) {
 	}
 	defer file.Close()
 
+	return ParseConf(file)
+}
+
+// ParseConf parses an INI document from r.
+func ParseConf(r io.Reader) (*INI, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
 	ini := NewINI()
-	scanner := bufio.NewScanner(file)
+	if bytes.Contains(data, []byte("\r\n")) {
+		ini.newline = "\r\n"
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	currentSection := ""
 
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
-			continue
+		line := parseINILine(strings.TrimSuffix(scanner.Text(), "\r"), currentSection)
+		if line.kind == iniSection {
+			currentSection = line.section
 		}
+		ini.lines = append(ini.lines, line)
+	}
 
-		// Check for section header
-		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			currentSection = line[1 : len(line)-1]
-			// Ensure the section map is created
-			if _, ok := ini.data[currentSection]; !ok {
-				ini.data[currentSection] = make(map[string]string)
-			}
-			continue
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	return ini, nil
+}
+
+func parseINILine(raw, section string) *iniLine {
+	line := &iniLine{raw: raw, section: section}
+	trimmed := strings.TrimSpace(raw)
+
+	// Skip empty lines and comments
+	if trimmed == "" || strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "#") {
+		return line
+	}
+
+	// Check for section header, possibly followed by a comment
+	if strings.HasPrefix(trimmed, "[") {
+		if end := strings.Index(trimmed, "]"); end > 0 {
+			line.kind = iniSection
+			line.section = strings.TrimSpace(trimmed[1:end])
 		}
+		return line
+	}
 
-		// Check for key-value pair
-		if strings.Contains(line, "=") {
-			parts := strings.SplitN(line, "=", 2)
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
+	// Check for key-value pair
+	eq := strings.Index(raw, "=")
+	if eq < 0 {
+		return line
+	}
 
-			if currentSection != "" {
-				ini.data[currentSection][key] = value
+	line.kind = iniKey
+	line.key = strings.TrimSpace(raw[:eq])
+	line.valueAt = eq + 1
+	for line.valueAt < len(raw) && (raw[line.valueAt] == ' ' || raw[line.valueAt] == '\t') {
+		line.valueAt++
+	}
+
+	// A ; or # after whitespace starts a trailing comment, unless quoted
+	line.valueEnd = len(raw)
+	quoted := false
+	for n := line.valueAt; n < len(raw); n++ {
+		switch raw[n] {
+		case '"':
+			quoted = !quoted
+		case ';', '#':
+			if !quoted && n > line.valueAt && (raw[n-1] == ' ' || raw[n-1] == '\t') {
+				line.valueEnd = n
+				n = len(raw)
 			}
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+	for line.valueEnd > line.valueAt && (raw[line.valueEnd-1] == ' ' || raw[line.valueEnd-1] == '\t') {
+		line.valueEnd--
 	}
+	line.value = raw[line.valueAt:line.valueEnd]
 
-	return ini, nil
+	return line
 }
 
-// Get retrieves a value for a given section and key.
+// GetConf retrieves a value for a given section and key.
 // It returns the value and a boolean indicating if the key was found.
 func (i *INI) GetConf(section, key string) (string, bool) {
 	i.mu.RLock()
 	defer i.mu.RUnlock()
 
-	sec, ok := i.data[section]
-	if !ok {
-		return "", false
+	if line := i.lastKey(section, key); line != nil {
+		return line.value, true
 	}
-	val, ok := sec[key]
-	return val, ok
+
+	return "", false
+}
+
+// GetAllConf returns every value of a key repeated in a section, in file order.
+func (i *INI) GetAllConf(section, key string) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	values := []string{}
+	for _, line := range i.lines {
+		if line.kind == iniKey && line.section == section && line.key == key {
+			values = append(values, line.value)
+		}
+	}
+
+	return values
 }
 
-// Set adds or updates a value for a given section and key.
-// If the section does not exist, it will be created.
+// SetConf adds or updates a value for a given section and key.
+// An existing line keeps its key, spacing and position; a new key goes after
+// the last key of its section, and a missing section is appended.
 func (i *INI) SetConf(section, key, value string) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	if _, ok := i.data[section]; !ok {
-		i.data[section] = make(map[string]string)
+	if line := i.lastKey(section, key); line != nil {
+		if line.value != value {
+			line.raw = line.raw[:line.valueAt] + value + line.raw[line.valueEnd:]
+			line.valueEnd = line.valueAt + len(value)
+			line.value = value
+		}
+		return
 	}
-	i.data[section][key] = value
+
+	i.insertKey(section, key, value, i.sectionEnd(section))
 }
 
-// SaveFile writes the INI data to a file at the given path.
-func (i *INI) SaveConf(filename string) error {
+// AddConf adds another occurrence of a key that may repeat, such as
+// extension in php.ini. It goes after the last occurrence of the key in its
+// section, or where SetConf would put a new key.
+func (i *INI) AddConf(section, key, value string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	at := i.sectionEnd(section)
+	for n, line := range i.lines {
+		if line.kind == iniKey && line.section == section && line.key == key {
+			at = n + 1
+		}
+	}
+
+	i.insertKey(section, key, value, at)
+}
+
+// Uncomment turns the first commented-out key of a section back into a key
+// line when match accepts its value, and reports whether it did. Only lines
+// of the form ;key=value count: a ; followed by a space starts prose, e.g.
+// the examples in the comments of php.ini.
+func (i *INI) Uncomment(section, key string, match func(value string) bool) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for n, line := range i.lines {
+		if line.kind != iniOther || line.section != section {
+			continue
+		}
+
+		raw := strings.TrimLeft(line.raw, " \t")
+		if !strings.HasPrefix(raw, ";"+key) {
+			continue
+		}
+
+		uncommented := parseINILine(raw[1:], section)
+		if uncommented.kind != iniKey || uncommented.key != key || !match(uncommented.value) {
+			continue
+		}
+
+		i.lines[n] = uncommented
+		return true
+	}
+
+	return false
+}
+
+// DeleteConf removes every occurrence of a key and reports whether there was any.
+// The section header stays, together with its comments.
+func (i *INI) DeleteConf(section, key string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	kept := i.lines[:0]
+	found := false
+	for _, line := range i.lines {
+		if line.kind == iniKey && line.section == section && line.key == key {
+			found = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	i.lines = kept
+
+	return found
+}
+
+// Sections returns the section names in file order. The global section ""
+// comes first when there are keys before any header.
+func (i *INI) Sections() []string {
 	i.mu.RLock()
 	defer i.mu.RUnlock()
 
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+	sections := []string{}
+	seen := map[string]bool{}
+	for _, line := range i.lines {
+		if (line.kind == iniSection || line.kind == iniKey) && !seen[line.section] {
+			seen[line.section] = true
+			sections = append(sections, line.section)
+		}
 	}
-	defer file.Close()
 
-	writer := bufio.NewWriter(file)
+	return sections
+}
 
-	for section, kv := range i.data {
-		// Write section header
-		if _, err := fmt.Fprintf(writer, "[%s]\n", section); err != nil {
-			return err
+// Keys returns the distinct keys of a section in file order.
+func (i *INI) Keys(section string) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, line := range i.lines {
+		if line.kind == iniKey && line.section == section && !seen[line.key] {
+			seen[line.key] = true
+			keys = append(keys, line.key)
 		}
-		// Write key-value pairs
-		for key, value := range kv {
-			if _, err := fmt.Fprintf(writer, "%s=%s\n", key, value); err != nil {
-				return err
-			}
+	}
+
+	return keys
+}
+
+// Bytes renders the document, unchanged lines exactly as they were read.
+func (i *INI) Bytes() []byte {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var buf bytes.Buffer
+	for _, line := range i.lines {
+		buf.WriteString(line.raw)
+		buf.WriteString(i.newline)
+	}
+
+	return buf.Bytes()
+}
+
+// SaveConf writes the INI document to a file at the given path.
+func (i *INI) SaveConf(filename string) error {
+	if err := os.WriteFile(filename, i.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+// insertKey inserts key=value at index at, or in a new section appended to
+// the document when at is negative.
+func (i *INI) insertKey(section, key, value string, at int) {
+	line := &iniLine{raw: key + "=" + value, kind: iniKey, section: section, key: key, value: value, valueAt: len(key) + 1, valueEnd: len(key) + 1 + len(value)}
+
+	if at < 0 {
+		if len(i.lines) > 0 && strings.TrimSpace(i.lines[len(i.lines)-1].raw) != "" {
+			i.lines = append(i.lines, &iniLine{section: section})
+		}
+		i.lines = append(i.lines, &iniLine{raw: "[" + section + "]", kind: iniSection, section: section}, line)
+		return
+	}
+
+	i.lines = append(i.lines[:at], append([]*iniLine{line}, i.lines[at:]...)...)
+}
+
+// lastKey returns the last line setting key in section, or nil.
+func (i *INI) lastKey(section, key string) *iniLine {
+	for n := len(i.lines) - 1; n >= 0; n-- {
+		line := i.lines[n]
+		if line.kind == iniKey && line.section == section && line.key == key {
+			return line
 		}
-		// Add a blank line for readability
-		if _, err := fmt.Fprintln(writer); err != nil {
-			return err
+	}
+
+	return nil
+}
+
+// sectionEnd returns the index right after the last key of section, or
+// after its header when it has no keys yet. It returns -1 when the section
+// does not exist. The global section always exists.
+func (i *INI) sectionEnd(section string) int {
+	end := -1
+	if section == "" {
+		end = 0
+	}
+
+	for n, line := range i.lines {
+		if line.section != section {
+			continue
+		}
+
+		if line.kind == iniKey || (line.kind == iniSection && end < 0) {
+			end = n + 1
 		}
 	}
 
-	return writer.Flush()
+	return end
 }
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,8 +14,8 @@ type WriteCounter struct {
 }
 
 func (wc WriteCounter) PrintProgress() {
-	fmt.Printf("\r%s", strings.Repeat(" ", 35))
-	fmt.Printf("\rDownloading... %s complete", Bytes(wc.Total))
+	fmt.Fprintf(logOutput, "\r%s", strings.Repeat(" ", 35))
+	fmt.Fprintf(logOutput, "\rDownloading... %s complete", Bytes(wc.Total))
 }
 
 func (wc *WriteCounter) Write(p []byte) (int, error) {
@@ -24,37 +25,59 @@ func (wc *WriteCounter) Write(p []byte) (int, error) {
 	return n, nil
 }
 
-func DownloadFile(filepath string, url string) error {
+// DownloadFile downloads url to filepath through a filepath.tmp file, which
+// is only renamed once complete. The .tmp file is removed when the download
+// fails or ctx is cancelled.
+func DownloadFile(ctx context.Context, filepath string, url string) (err error) {
 
-	_, err := os.Stat(filepath)
+	_, err = os.Stat(filepath)
 	if err == nil {
-		fmt.Printf("File %s already exists\n", filepath)
+		fmt.Fprintf(logOutput, "File %s already exists\n", filepath)
 
 		// For now we assume the previous download is not corrupt just we just skip it
 		return nil
 	}
 
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
 	out, err := os.Create(filepath + ".tmp")
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(filepath + ".tmp")
+		}
+	}()
 
-	response, err := http.Get(url)
+	response, err := http.DefaultClient.Do(request)
 	if err != nil {
-		out.Close()
 		return err
 	}
 	defer response.Body.Close()
 
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s: %s", url, response.Status)
+	}
+
 	counter := &WriteCounter{}
 	if _, err = io.Copy(out, io.TeeReader(response.Body, counter)); err != nil {
-		out.Close()
+		fmt.Fprint(logOutput, "\n")
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return err
 	}
 
-	fmt.Print("\n")
+	fmt.Fprint(logOutput, "\n")
 
-	out.Close()
+	if err = out.Close(); err != nil {
+		return err
+	}
 
 	if err = os.Rename(filepath+".tmp", filepath); err != nil {
 		return err
@@ -7,11 +7,26 @@ import (
 	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
+// logOutput is stderr so stdout only carries command results, e.g. for
+// $(wamp config get apache.port) or --output json.
+var logOutput io.Writer = os.Stderr
+
+// SetLogOutput redirects PrintLog and the output of the tools wamp runs.
+func SetLogOutput(w io.Writer) {
+	logOutput = w
+}
+
+// LogOutput returns the writer used by PrintLog.
+func LogOutput() io.Writer {
+	return logOutput
+}
+
 func PrintLog(prefix string) *log.Logger {
-	return log.New(os.Stdout, fmt.Sprintf("[%s]: ", prefix), log.LstdFlags)
+	return log.New(logOutput, fmt.Sprintf("[%s]: ", prefix), log.LstdFlags)
 }
 
 func Check(err error) {
@@ -35,6 +50,27 @@ func DirExists(path string) (bool, error) {
 	return false, err
 }
 
+// SubDirs lists the names of the directories directly under dir, e.g. the
+// installed versions under bin/php. A missing dir yields no names.
+func SubDirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
 func NormalizePath(original string) string {
 	return strings.ReplaceAll(original, "\\", "/")
 }
@@ -69,6 +105,72 @@ func CopyFile(src, dst string) error {
 	return nil
 }
 
+// ErrSourceKept is returned by Move when a directory was copied to dst but
+// parts of src could not be removed, e.g. files locked by a running process.
+// dst is complete.
+var ErrSourceKept = errors.New("source not fully removed")
+
+// Move moves the file or directory src to dst, creating the parent of dst.
+// dst must not exist. When a rename is impossible, e.g. across drives or
+// with locked files, src is copied and then removed; a failed copy leaves
+// src intact and removes the partial dst, and so does a copied file whose
+// src can not be removed. A copied directory whose src can only be removed
+// partly returns ErrSourceKept.
+func Move(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("unable to move %s: %s exists", src, dst)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		return CopyFile(p, target)
+	})
+	if err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+
+	if !srcInfo.IsDir() {
+		if err := os.Remove(src); err != nil {
+			os.Remove(dst)
+			return err
+		}
+		return nil
+	}
+
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("%w: %s copied to %s: %v", ErrSourceKept, src, dst, err)
+	}
+
+	return nil
+}
+
 func CleanDirs(dirs ...string) error {
 	for i := range dirs {
 		if err := os.RemoveAll(dirs[i]); err != nil {
@@ -2,6 +2,7 @@ package util
 
 import (
 	"archive/zip"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -9,7 +10,10 @@ import (
 	"strings"
 )
 
-func Unzip(src, dest string) error {
+// Unzip extracts src into dest, checking ctx between and during files. When
+// dest did not exist beforehand it is removed again if extraction fails or
+// is cancelled, so no half-extracted directory is left behind.
+func Unzip(ctx context.Context, src, dest string) (err error) {
 	r, err := zip.OpenReader(src)
 	if err != nil {
 		return err
@@ -20,8 +24,21 @@ func Unzip(src, dest string) error {
 		}
 	}()
 
+	destExist, err := DirExists(dest)
+	if err != nil {
+		return err
+	}
+
 	os.MkdirAll(dest, 0755)
 
+	if !destExist {
+		defer func() {
+			if err != nil {
+				os.RemoveAll(dest)
+			}
+		}()
+	}
+
 	extractAndWriteFile := func(f *zip.File) error {
 		rc, err := f.Open()
 		if err != nil {
@@ -53,7 +70,7 @@ func Unzip(src, dest string) error {
 				}
 			}()
 
-			_, err = io.Copy(f, rc)
+			_, err = io.Copy(f, &contextReader{ctx: ctx, r: rc})
 			if err != nil {
 				return err
 			}
@@ -63,11 +80,28 @@ func Unzip(src, dest string) error {
 	}
 
 	for _, f := range r.File {
-		err := extractAndWriteFile(f)
-		if err != nil {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if err = extractAndWriteFile(f); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// contextReader stops a copy as soon as ctx is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return cr.r.Read(p)
+}
@@ -0,0 +1,196 @@
+package wamp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// ValueType is the type of a wamp.ini value.
+type ValueType int
+
+const (
+	StringValue ValueType = iota
+	IntValue
+	BoolValue
+	PathValue
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case IntValue:
+		return "int"
+	case BoolValue:
+		return "bool"
+	case PathValue:
+		return "path"
+	default:
+		return "string"
+	}
+}
+
+// Setting describes a key wamp.ini understands, addressed as "section.key".
+type Setting struct {
+	Section     string
+	Key         string
+	Type        ValueType
+	Default     string
+	Description string
+	// Allowed returns the accepted values, e.g. the installed versions.
+	// A nil Allowed accepts any value of Type.
+	Allowed func(l Layout) ([]string, error)
+}
+
+// Settings is the schema of wamp.ini.
+var Settings = []Setting{
+	{
+		Section:     "apache",
+		Key:         "active",
+		Default:     ApacheVersion,
+		Description: "Apache version used by apache start/stop and the site commands",
+		Allowed:     func(l Layout) ([]string, error) { return util.SubDirs(l.ApacheDir()) },
+	},
+	{
+		Section:     "apache",
+		Key:         "port",
+		Type:        IntValue,
+		Default:     "80",
+		Description: "HTTP port of Apache and the site vhosts, applied on apache start",
+	},
+	{
+		Section:     "mysql",
+		Key:         "active",
+		Default:     MariaDBVersion,
+		Description: "MySQL/MariaDB version used by mysql start/stop",
+		Allowed:     func(l Layout) ([]string, error) { return util.SubDirs(l.MysqlDir()) },
+	},
+	{
+		Section:     "mysql",
+		Key:         "port",
+		Type:        IntValue,
+		Default:     "3306",
+		Description: "Port of MySQL/MariaDB, applied on mysql start",
+	},
+	{
+		Section:     "php",
+		Key:         "default",
+		Default:     "php-8.3",
+		Description: "PHP version used by site add when --php is not given, matched like --php",
+	},
+	{
+		Section:     "site",
+		Key:         "tld",
+		Default:     "test",
+		Description: "Top-level domain appended to site names without a dot, e.g. shop becomes shop.test, and used for parked directories",
+	},
+	{
+		Section:     "wamp",
+		Key:         "schema_version",
+		Type:        IntValue,
+		Description: "Version of the wamp.ini schema, maintained by wamp migrate",
+	},
+	{
+		Section:     "profile",
+		Key:         "active",
+		Description: "Profile last applied by profile use",
+	},
+	{
+		Section:     "paths",
+		Key:         "bin",
+		Type:        PathValue,
+		Default:     "bin",
+		Description: "Directory holding apache, mysql, php and etc, relative to the wamp dir",
+	},
+	{
+		Section:     "paths",
+		Key:         "www",
+		Type:        PathValue,
+		Default:     "www",
+		Description: "Directory holding the sites, relative to the wamp dir",
+	},
+	{
+		Section:     "paths",
+		Key:         "tmp",
+		Type:        PathValue,
+		Default:     "tmp",
+		Description: "Directory for downloads and runtime files, relative to the wamp dir",
+	},
+}
+
+// Name returns the "section.key" name of the setting.
+func (s Setting) Name() string {
+	return s.Section + "." + s.Key
+}
+
+// CheckType checks that value parses as the type of the setting.
+func (s Setting) CheckType(value string) error {
+	switch s.Type {
+	case IntValue:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s must be an integer, got %q", s.Name(), value)
+		}
+	case BoolValue:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", s.Name(), value)
+		}
+	case PathValue:
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("%s must not be empty", s.Name())
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that value has the right type and is one of the allowed values.
+func (s Setting) Validate(l Layout, value string) error {
+	if err := s.CheckType(value); err != nil {
+		return err
+	}
+
+	if s.Allowed == nil {
+		return nil
+	}
+
+	allowed, err := s.Allowed(l)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range allowed {
+		if v == value {
+			return nil
+		}
+	}
+
+	if len(allowed) == 0 {
+		return fmt.Errorf("invalid value %q for %s: nothing is installed", value, s.Name())
+	}
+
+	return fmt.Errorf("invalid value %q for %s, expected one of: %s", value, s.Name(), strings.Join(allowed, ", "))
+}
+
+// LookupSetting returns the setting called name ("section.key").
+func LookupSetting(name string) (Setting, error) {
+	for _, s := range Settings {
+		if s.Name() == name {
+			return s, nil
+		}
+	}
+
+	return Setting{}, fmt.Errorf("unknown config key %q, see 'wamp config list'", name)
+}
+
+// SettingNames returns the names of every setting, sorted.
+func SettingNames() []string {
+	names := make([]string, 0, len(Settings))
+	for _, s := range Settings {
+		names = append(names, s.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
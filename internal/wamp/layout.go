@@ -0,0 +1,95 @@
+package wamp
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// Layout holds the locations of a wamp stack. Every directory defaults to a
+// child of WampDir; bin, www and tmp can be moved through the [paths]
+// section of wamp.ini, e.g. to keep www on another drive.
+type Layout struct {
+	WampDir  string
+	ConfPath string
+	BinDir   string
+	WWWDir   string
+	TmpDir   string
+	// Config resolves the wamp.ini settings, see Resolver.
+	Config *Resolver
+}
+
+// DefaultLayout returns the layout with every directory inside wampDir.
+func DefaultLayout(wampDir string) Layout {
+	return Layout{
+		WampDir:  wampDir,
+		ConfPath: path.Join(wampDir, "wamp.ini"),
+		BinDir:   path.Join(wampDir, "bin"),
+		WWWDir:   path.Join(wampDir, "www"),
+		TmpDir:   path.Join(wampDir, "tmp"),
+		Config:   NewResolver(nil, path.Join(wampDir, "wamp.ini"), nil),
+	}
+}
+
+// LoadLayout returns the layout of the stack in wampDir. confPath selects an
+// alternative wamp.ini; when empty, wamp.ini inside wampDir is used. A
+// missing wamp.ini is not an error since it only exists after install.
+// overrides are the settings given on the command line, see Resolver.
+func LoadLayout(wampDir, confPath string, overrides map[string]string) (Layout, error) {
+	layout := DefaultLayout(wampDir)
+	if confPath != "" {
+		layout.ConfPath = confPath
+	}
+
+	// an unreadable wamp.ini still yields the default layout, so the
+	// commands that do not need it can run
+	conf, err := util.LoadConf(layout.ConfPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		layout.Config = NewResolver(nil, layout.ConfPath, overrides)
+		return layout, err
+	}
+	layout.Config = NewResolver(conf, layout.ConfPath, overrides)
+
+	for key, dir := range map[string]*string{"bin": &layout.BinDir, "www": &layout.WWWDir, "tmp": &layout.TmpDir} {
+		value := layout.Config.Get("paths." + key)
+		if value == "" {
+			continue
+		}
+
+		if !filepath.IsAbs(value) {
+			value = path.Join(wampDir, util.NormalizePath(value))
+		}
+		*dir = value
+	}
+
+	return layout, nil
+}
+
+func (l Layout) ApacheDir() string {
+	return path.Join(l.BinDir, "apache")
+}
+
+func (l Layout) PHPDir() string {
+	return path.Join(l.BinDir, "php")
+}
+
+func (l Layout) MysqlDir() string {
+	return path.Join(l.BinDir, "mysql")
+}
+
+func (l Layout) EtcDir() string {
+	return path.Join(l.BinDir, "etc")
+}
+
+// SitesPath returns the site registry, see site.Registry.
+func (l Layout) SitesPath() string {
+	return path.Join(l.WampDir, "sites.json")
+}
+
+// TrashDir returns the directory removed sites are kept in, see site.Trashed.
+func (l Layout) TrashDir() string {
+	return path.Join(l.WampDir, "trash")
+}
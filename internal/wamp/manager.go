@@ -1,12 +1,14 @@
 package wamp
 
 import (
+	"context"
 	"errors"
 	"io/fs"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/aziyan99/wamp/internal/apache"
@@ -14,8 +16,16 @@ import (
 	"github.com/aziyan99/wamp/internal/util"
 )
 
+// Versions installed by Install.
+const (
+	PHPVersion     = "php-8.3.8-nts-Win32-vs16-x64"
+	ApacheVersion  = "httpd-2.4.65-250724-Win64-VS17"
+	MariaDBVersion = "mariadb-11.8.3-winx64"
+)
+
 type Manager struct {
 	wampDir   string
+	confPath  string
 	binDir    string
 	apacheDir string
 	phpDir    string
@@ -24,16 +34,16 @@ type Manager struct {
 	tmpDir    string
 }
 
-func New(wampDir string) *Manager {
-	binDir := path.Join(wampDir, "bin")
+func New(layout Layout) *Manager {
 	return &Manager{
-		wampDir:   wampDir,
-		binDir:    binDir,
-		apacheDir: path.Join(binDir, "apache"),
-		phpDir:    path.Join(binDir, "php"),
-		mysqlDir:  path.Join(binDir, "mysql"),
-		wwwDir:    path.Join(wampDir, "www"),
-		tmpDir:    path.Join(wampDir, "tmp"),
+		wampDir:   layout.WampDir,
+		confPath:  layout.ConfPath,
+		binDir:    layout.BinDir,
+		apacheDir: layout.ApacheDir(),
+		phpDir:    layout.PHPDir(),
+		mysqlDir:  layout.MysqlDir(),
+		wwwDir:    layout.WWWDir,
+		tmpDir:    layout.TmpDir,
 	}
 }
 
@@ -130,35 +140,57 @@ func (m *Manager) Init() error {
 	return nil
 }
 
-func (m *Manager) Install() error {
-	var err error
+// Install sets up PHP, Apache, MariaDB, wamp.ini and the etc tools. If it
+// fails or ctx is cancelled, everything created so far is removed again so
+// the next run starts from a clean state.
+func (m *Manager) Install(ctx context.Context) (err error) {
+	// created lists what this run added, removed in reverse order on failure
+	created := []string{}
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		if ctx.Err() != nil {
+			util.PrintLog("WARN").Println("Install cancelled, removing partial installation...")
+		}
 
-	phpVersion := "php-8.3.8-nts-Win32-vs16-x64"
+		for i := len(created) - 1; i >= 0; i-- {
+			if rmErr := os.RemoveAll(created[i]); rmErr != nil {
+				util.PrintLog("ERROR").Printf("unable to remove %s: %v\n", created[i], rmErr)
+			}
+		}
+	}()
+
+	phpVersion := PHPVersion
 	phpManager := php.New(m.phpDir, m.tmpDir)
-	err = phpManager.Install(phpVersion)
+	err = phpManager.Install(ctx, phpVersion)
 	if err != nil {
 		return err
 	}
+	created = append(created, path.Join(m.phpDir, phpVersion))
 
-	a2Version := "httpd-2.4.65-250724-Win64-VS17"
+	a2Version := ApacheVersion
 	apacheManager := apache.New(m.apacheDir, m.tmpDir)
-	err = apacheManager.Install(a2Version, path.Join(m.phpDir, phpVersion))
+	err = apacheManager.Install(ctx, a2Version, path.Join(m.phpDir, phpVersion))
 	if err != nil {
 		return err
 	}
+	created = append(created, path.Join(m.apacheDir, a2Version))
 
-	mariadbVersion := "mariadb-11.8.3-winx64"
+	mariadbVersion := MariaDBVersion
 	if _, err = os.Stat(path.Join(m.mysqlDir, mariadbVersion)); err == nil {
 		return errors.New("mysql installation already exists")
 	}
+	created = append(created, path.Join(m.mysqlDir, mariadbVersion))
 	util.PrintLog("INFO").Println("Downloading MariaDB...")
-	err = util.DownloadFile(path.Join(m.tmpDir, mariadbVersion+".zip"), "http://downloads.mariadb.org/rest-api/mariadb/11.8.3/mariadb-11.8.3-winx64.zip")
+	err = util.DownloadFile(ctx, path.Join(m.tmpDir, mariadbVersion+".zip"), "http://downloads.mariadb.org/rest-api/mariadb/11.8.3/mariadb-11.8.3-winx64.zip")
 	if err != nil {
 		return err
 	}
 
 	util.PrintLog("INFO").Println("Extracting MariaDB...")
-	err = util.Unzip(path.Join(m.tmpDir, mariadbVersion+".zip"), path.Join(m.mysqlDir, mariadbVersion))
+	err = util.Unzip(ctx, path.Join(m.tmpDir, mariadbVersion+".zip"), path.Join(m.mysqlDir, mariadbVersion))
 	if err != nil {
 		return err
 	}
@@ -212,8 +244,8 @@ func (m *Manager) Install() error {
 		return err
 	}
 
-	mariaDdInstallDbCmd := exec.Command(path.Join(m.mysqlDir, mariadbVersion, "bin", "mariadb-install-db.exe"))
-	mariaDdInstallDbCmd.Stdout = os.Stdout
+	mariaDdInstallDbCmd := exec.CommandContext(ctx, path.Join(m.mysqlDir, mariadbVersion, "bin", "mariadb-install-db.exe"))
+	mariaDdInstallDbCmd.Stdout = util.LogOutput()
 	err = mariaDdInstallDbCmd.Run()
 	if err != nil {
 		return err
@@ -224,10 +256,33 @@ func (m *Manager) Install() error {
 	}
 
 	// setup wamp conf
-	if _, err := os.Stat(path.Join(m.wampDir, "wamp.ini")); err != nil && errors.Is(err, fs.ErrNotExist) {
+	if _, err := os.Stat(m.confPath); err != nil && errors.Is(err, fs.ErrNotExist) {
 		util.PrintLog("INFO").Println("Setup wamp.conf...")
-		wampConf := []byte("[apache]\nactive=" + a2Version + "\n[mysql]\nactive=" + mariadbVersion)
-		if err = os.WriteFile(path.Join(m.wampDir, "wamp.ini"), wampConf, 0755); err != nil {
+		wampConf := []byte("[wamp]\nschema_version=" + strconv.Itoa(SchemaVersion) + "\n[apache]\nactive=" + a2Version + "\n[mysql]\nactive=" + mariadbVersion)
+		if err = os.WriteFile(m.confPath, wampConf, 0755); err != nil {
+			return err
+		}
+		created = append(created, m.confPath)
+	} else if err == nil {
+		// wamp.ini may already exist to relocate [paths] before install
+		conf, err := util.LoadConf(m.confPath)
+		if err != nil {
+			return err
+		}
+
+		if _, found := conf.GetConf("apache", "active"); !found {
+			conf.SetConf("apache", "active", a2Version)
+		}
+
+		if _, found := conf.GetConf("mysql", "active"); !found {
+			conf.SetConf("mysql", "active", mariadbVersion)
+		}
+
+		if _, found := conf.GetConf("wamp", "schema_version"); !found {
+			conf.SetConf("wamp", "schema_version", strconv.Itoa(SchemaVersion))
+		}
+
+		if err = conf.SaveConf(m.confPath); err != nil {
 			return err
 		}
 	}
@@ -249,13 +304,14 @@ func (m *Manager) Install() error {
 	}
 
 	util.PrintLog("INFO").Println("Downloading mkcert...")
-	err = util.DownloadFile(path.Join(m.binDir, "etc", "mkcert.exe"), "https://github.com/FiloSottile/mkcert/releases/download/v1.4.4/mkcert-v1.4.4-windows-amd64.exe")
+	err = util.DownloadFile(ctx, path.Join(m.binDir, "etc", "mkcert.exe"), "https://github.com/FiloSottile/mkcert/releases/download/v1.4.4/mkcert-v1.4.4-windows-amd64.exe")
 	if err != nil {
 		return err
 	}
+	created = append(created, path.Join(m.binDir, "etc", "mkcert.exe"))
 
-	mkcertInstallCmd := exec.Command(path.Join(m.binDir, "etc", "mkcert.exe"), "-install")
-	mkcertInstallCmd.Stdout = os.Stdout
+	mkcertInstallCmd := exec.CommandContext(ctx, path.Join(m.binDir, "etc", "mkcert.exe"), "-install")
+	mkcertInstallCmd.Stdout = util.LogOutput()
 	err = mkcertInstallCmd.Run()
 	if err != nil {
 		return err
@@ -264,7 +320,7 @@ func (m *Manager) Install() error {
 	util.PrintLog("INFO").Println("mkcert installed")
 
 	util.PrintLog("INFO").Println("Downloading hostsrw...")
-	err = util.DownloadFile(path.Join(m.binDir, "etc", "hostsrw.exe"), "https://github.com/aziyan99/hostsrw/releases/download/v2.3.2/hostsrw.exe")
+	err = util.DownloadFile(ctx, path.Join(m.binDir, "etc", "hostsrw.exe"), "https://github.com/aziyan99/hostsrw/releases/download/v2.3.2/hostsrw.exe")
 	if err != nil {
 		return err
 	}
@@ -272,7 +328,7 @@ func (m *Manager) Install() error {
 	util.PrintLog("INFO").Println("hostsrw installed")
 
 	util.PrintLog("INFO").Println("Downloading corn...")
-	err = util.DownloadFile(path.Join(m.binDir, "etc", "corn.exe"), "https://github.com/aziyan99/corn/releases/download/v0.1.0/corn.exe")
+	err = util.DownloadFile(ctx, path.Join(m.binDir, "etc", "corn.exe"), "https://github.com/aziyan99/corn/releases/download/v0.1.0/corn.exe")
 	if err != nil {
 		return err
 	}
@@ -289,7 +345,7 @@ func (m *Manager) Install() error {
 
 	// install composer: https://getcomposer.org/download/latest-stable/composer.phar
 	util.PrintLog("INFO").Println("Installing composer...")
-	err = util.DownloadFile(path.Join(m.binDir, "etc", "composer.phar"), "https://getcomposer.org/download/latest-stable/composer.phar")
+	err = util.DownloadFile(ctx, path.Join(m.binDir, "etc", "composer.phar"), "https://getcomposer.org/download/latest-stable/composer.phar")
 	if err != nil {
 		return err
 	}
@@ -304,7 +360,7 @@ func (m *Manager) Clean() error {
 	util.PrintLog("INFO").Println("Cleaning all wamp directories...")
 
 	mkcertUninstallCmd := exec.Command(path.Join(m.binDir, "etc", "mkcert.exe"), "-uninstall")
-	mkcertUninstallCmd.Stdout = os.Stdout
+	mkcertUninstallCmd.Stdout = util.LogOutput()
 	err = mkcertUninstallCmd.Run()
 	if err != nil {
 		return err
@@ -314,11 +370,11 @@ func (m *Manager) Clean() error {
 		return err
 	}
 
-	if _, err = os.Stat(path.Join(m.wampDir, "wamp.ini")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+	if _, err = os.Stat(m.confPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
 
-	if err = os.Remove(path.Join(m.wampDir, "wamp.ini")); err != nil {
+	if err = os.Remove(m.confPath); err != nil {
 		return err
 	}
 
@@ -0,0 +1,259 @@
+package wamp
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/aziyan99/wamp/internal/site"
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// SchemaVersion is the version of the wamp.ini schema and stack layout this
+// binary writes. Bump it together with a new entry in Migrations.
+const SchemaVersion = 3
+
+// Migration upgrades wamp.ini and the stack layout to Version.
+type Migration struct {
+	Version     int
+	Description string
+	Run         func(l Layout, conf *util.INI) error
+}
+
+// Migrations upgrade old stacks step by step, ordered by Version. A stack
+// without [wamp] schema_version is at version 0.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "set [apache] active and [mysql] active from the installed versions",
+		Run:         migrateActiveVersions,
+	},
+	{
+		Version:     2,
+		Description: "move mkcert, hostsrw, corn and composer into bin/etc",
+		Run:         migrateEtcTools,
+	},
+	{
+		Version:     3,
+		Description: "record the sites of the active Apache in sites.json",
+		Run:         migrateSiteRegistry,
+	},
+}
+
+// MigrateResult describes a Migrate run.
+type MigrateResult struct {
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Backup string `json:"backup,omitempty"`
+	// SitesBackup is the copy of sites.json, when it existed
+	SitesBackup string   `json:"sites_backup,omitempty"`
+	Applied     []string `json:"applied"`
+}
+
+// ConfSchemaVersion returns the schema version recorded in conf.
+func ConfSchemaVersion(conf *util.INI) (int, error) {
+	value, found := conf.GetConf("wamp", "schema_version")
+	if !found {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid [wamp] schema_version %q in wamp.ini", value)
+	}
+
+	return version, nil
+}
+
+// PendingMigrations returns the migrations a stack at version still needs.
+func PendingMigrations(version int) []Migration {
+	pending := []Migration{}
+	for _, m := range Migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending
+}
+
+// Migrate upgrades the stack in l to SchemaVersion. wamp.ini and, when it
+// exists, sites.json are backed up next to themselves before anything
+// changes, and schema_version is saved after every migration so a failed
+// run resumes where it stopped. A missing wamp.ini, i.e. a stack that is
+// not installed, needs no migration. A wamp.ini written by a newer wamp is
+// refused. With dryRun nothing is changed and the pending migrations are
+// returned as applied.
+func Migrate(l Layout, dryRun bool) (*MigrateResult, error) {
+	conf, err := util.LoadConf(l.ConfPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &MigrateResult{From: SchemaVersion, To: SchemaVersion, Applied: []string{}}, nil
+		}
+		return nil, err
+	}
+
+	version, err := ConfSchemaVersion(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("%s uses schema version %d but this wamp only supports up to %d, please upgrade wamp", l.ConfPath, version, SchemaVersion)
+	}
+
+	result := &MigrateResult{From: version, To: version, Applied: []string{}}
+	pending := PendingMigrations(version)
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	if dryRun {
+		for _, m := range pending {
+			result.Applied = append(result.Applied, m.Description)
+		}
+		result.To = SchemaVersion
+		return result, nil
+	}
+
+	result.Backup = fmt.Sprintf("%s.v%d.bak", l.ConfPath, version)
+	if err := util.CopyFile(l.ConfPath, result.Backup); err != nil {
+		return nil, fmt.Errorf("unable to back up wamp.ini: %w", err)
+	}
+	util.PrintLog("INFO").Printf("Backed up wamp.ini to %s\n", result.Backup)
+
+	// migrations may record sites, e.g. migration 3
+	if _, err := os.Stat(l.SitesPath()); err == nil {
+		result.SitesBackup = fmt.Sprintf("%s.v%d.bak", l.SitesPath(), version)
+		if err := util.CopyFile(l.SitesPath(), result.SitesBackup); err != nil {
+			return nil, fmt.Errorf("unable to back up sites.json: %w", err)
+		}
+		util.PrintLog("INFO").Printf("Backed up sites.json to %s\n", result.SitesBackup)
+	}
+
+	for _, m := range pending {
+		util.PrintLog("INFO").Printf("Migrating to schema version %d: %s\n", m.Version, m.Description)
+		if err := m.Run(l, conf); err != nil {
+			backups := result.Backup
+			if result.SitesBackup != "" {
+				backups += " and " + result.SitesBackup
+			}
+			return result, fmt.Errorf("migration to schema version %d failed, backups in %s: %w", m.Version, backups, err)
+		}
+
+		conf.SetConf("wamp", "schema_version", strconv.Itoa(m.Version))
+		if err := conf.SaveConf(l.ConfPath); err != nil {
+			return result, err
+		}
+
+		result.To = m.Version
+		result.Applied = append(result.Applied, m.Description)
+	}
+
+	return result, nil
+}
+
+// migrateActiveVersions fills in the active versions of stacks created
+// before [apache] active and [mysql] active existed, when exactly one
+// version is installed.
+func migrateActiveVersions(l Layout, conf *util.INI) error {
+	for section, dir := range map[string]string{"apache": l.ApacheDir(), "mysql": l.MysqlDir()} {
+		if _, found := conf.GetConf(section, "active"); found {
+			continue
+		}
+
+		installed, err := util.SubDirs(dir)
+		if err != nil {
+			return err
+		}
+
+		switch len(installed) {
+		case 0:
+			continue
+		case 1:
+			conf.SetConf(section, "active", installed[0])
+			util.PrintLog("INFO").Printf("Set [%s] active to %s\n", section, installed[0])
+		default:
+			util.PrintLog("WARN").Printf("Several versions in %s, choose one with 'wamp config set %s.active <version>'\n", dir, section)
+		}
+	}
+
+	return nil
+}
+
+// migrateEtcTools moves the helper tools of stacks installed before bin/etc
+// existed from bin into bin/etc.
+func migrateEtcTools(l Layout, conf *util.INI) error {
+	if err := os.MkdirAll(l.EtcDir(), 0755); err != nil {
+		return err
+	}
+
+	for _, name := range []string{"mkcert.exe", "hostsrw.exe", "corn.exe", "corntab", "composer.phar"} {
+		oldPath := path.Join(l.BinDir, name)
+		if _, err := os.Stat(oldPath); err != nil {
+			continue
+		}
+
+		newPath := path.Join(l.EtcDir(), name)
+		if _, err := os.Stat(newPath); err == nil {
+			util.PrintLog("WARN").Printf("%s exists in both %s and %s, keeping both\n", name, l.BinDir, l.EtcDir())
+			continue
+		}
+
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return err
+		}
+		util.PrintLog("INFO").Printf("Moved %s to %s\n", oldPath, newPath)
+	}
+
+	return nil
+}
+
+// migrateSiteRegistry creates the site registry from the vhosts of the
+// active Apache for stacks whose sites only existed as files.
+func migrateSiteRegistry(l Layout, conf *util.INI) error {
+	registry, err := site.LoadRegistry(l.SitesPath())
+	if err != nil {
+		return err
+	}
+
+	activeApache, found := conf.GetConf("apache", "active")
+	if !found {
+		return nil
+	}
+
+	confs, err := filepath.Glob(path.Join(l.ApacheDir(), activeApache, "conf", "sites-enabled", "*.conf"))
+	if err != nil {
+		return err
+	}
+
+	for _, confPath := range confs {
+		s, err := site.ParseVHost(confPath)
+		if err != nil {
+			return err
+		}
+
+		if s.Name == "" {
+			util.PrintLog("WARN").Printf("Skipping %s, it has no DOMAIN\n", confPath)
+			continue
+		}
+
+		if registry.Get(s.Name) != nil {
+			continue
+		}
+
+		if info, err := os.Stat(confPath); err == nil {
+			s.Created = info.ModTime().UTC().Truncate(time.Second)
+		}
+
+		registry.Put(s)
+		util.PrintLog("INFO").Printf("Recorded site %s\n", s.Name)
+	}
+
+	return registry.Save()
+}
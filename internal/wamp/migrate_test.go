@@ -0,0 +1,141 @@
+package wamp
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/aziyan99/wamp/internal/site"
+)
+
+// TestMigrateDotlessSite checks that a site created before names got a
+// domain, e.g. myapp, can still be found, removed and restored once
+// migration 3 recorded it.
+func TestMigrateDotlessSite(t *testing.T) {
+	wampDir := t.TempDir()
+	if err := os.WriteFile(path.Join(wampDir, "wamp.ini"), []byte("[wamp]\nschema_version = 2\n\n[apache]\nactive = httpd-a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	layout, err := LoadLayout(wampDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apacheDir := path.Join(layout.ApacheDir(), "httpd-a")
+	sitesEnabled := path.Join(apacheDir, "conf", "sites-enabled")
+	if err := os.MkdirAll(sitesEnabled, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := path.Join(sitesEnabled, "myapp.conf")
+	vhost := "Define ROOT \"" + path.Join(layout.WWWDir, "myapp") + "\"\nDefine DOMAIN \"myapp\"\n\n<VirtualHost *:80>\n    ServerName ${DOMAIN}\n    ServerAlias www.${DOMAIN}\n</VirtualHost>\n"
+	if err := os.WriteFile(conf, []byte(vhost), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Migrate(layout, false)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if result.SitesBackup != "" {
+		t.Errorf("SitesBackup = %q without a sites.json to back up", result.SitesBackup)
+	}
+
+	registry, err := site.LoadRegistry(layout.SitesPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registry.Get("myapp") == nil {
+		t.Fatalf("migration 3 did not record myapp, sites: %v", registry.Names())
+	}
+
+	siteManager := site.New(registry, layout.WWWDir, apacheDir, "", layout.EtcDir(), layout.TmpDir, layout.TrashDir())
+
+	sitename, err := siteManager.ExistingName("MyApp", "test")
+	if err != nil {
+		t.Fatalf("ExistingName: %v", err)
+	}
+	if sitename != "myapp" {
+		t.Fatalf("ExistingName(MyApp) = %q, want myapp", sitename)
+	}
+
+	if _, err := siteManager.Remove(sitename, false); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	if _, err := os.Stat(conf); !os.IsNotExist(err) {
+		t.Errorf("vhost of myapp still in sites-enabled: %v", err)
+	}
+
+	registry, err = site.LoadRegistry(layout.SitesPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registry.Get("myapp") != nil {
+		t.Error("myapp still in the registry after Remove")
+	}
+
+	siteManager = site.New(registry, layout.WWWDir, apacheDir, "", layout.EtcDir(), layout.TmpDir, layout.TrashDir())
+
+	sitename, err = siteManager.ExistingName("myapp", "test")
+	if err != nil || sitename != "myapp" {
+		t.Fatalf("ExistingName(myapp) of a trashed site = %q, %v, want myapp", sitename, err)
+	}
+
+	if _, err := siteManager.Restore(sitename); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	if _, err := os.Stat(conf); err != nil {
+		t.Errorf("vhost of myapp not restored: %v", err)
+	}
+	if registry.Get("myapp") == nil {
+		t.Error("myapp not in the registry after Restore")
+	}
+}
+
+// TestMigrateBacksUpSites checks that an existing sites.json is backed up
+// before the migrations, which may record sites in it, run.
+func TestMigrateBacksUpSites(t *testing.T) {
+	wampDir := t.TempDir()
+	if err := os.WriteFile(path.Join(wampDir, "wamp.ini"), []byte("[wamp]\nschema_version = 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	layout, err := LoadLayout(wampDir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := site.LoadRegistry(layout.SitesPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Put(&site.Site{Name: "shop.test"})
+	if err := registry.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := os.ReadFile(layout.SitesPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Migrate(layout, false)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	if want := layout.SitesPath() + ".v2.bak"; result.SitesBackup != want {
+		t.Fatalf("SitesBackup = %q, want %q", result.SitesBackup, want)
+	}
+
+	backup, err := os.ReadFile(result.SitesBackup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(backup) != string(original) {
+		t.Errorf("backup of sites.json differs from the original:\n%s\nwant:\n%s", backup, original)
+	}
+}
@@ -0,0 +1,117 @@
+package wamp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// ProfilePrefix starts the wamp.ini sections holding profiles, e.g. [profile.legacy].
+const ProfilePrefix = "profile."
+
+// profileKeys maps the keys of a profile section to the settings they select.
+var profileKeys = []struct {
+	key     string
+	setting string
+}{
+	{"apache", "apache.active"},
+	{"apache_port", "apache.port"},
+	{"mysql", "mysql.active"},
+	{"mysql_port", "mysql.port"},
+	{"php", "php.default"},
+}
+
+// Profile is a named Apache/MySQL/PHP combination from wamp.ini:
+//
+//	[profile.legacy]
+//	apache = httpd-2.4.54-win64-VS16
+//	mysql = mariadb-10.4.32-winx64
+//	php = php-7.4
+//	apache_port = 8080
+//	mysql_port = 3307
+//
+// Keys left out keep their current value when the profile is used.
+type Profile struct {
+	Name string `json:"name"`
+	// Settings maps setting names, e.g. apache.active, to their value.
+	Settings map[string]string `json:"settings"`
+}
+
+// Profiles returns the profiles defined in conf, ordered by name.
+func Profiles(conf *util.INI) []Profile {
+	profiles := []Profile{}
+	for _, section := range conf.Sections() {
+		if strings.HasPrefix(section, ProfilePrefix) {
+			profiles = append(profiles, profileFrom(conf, section))
+		}
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles
+}
+
+// LookupProfile returns the profile called name.
+func LookupProfile(conf *util.INI, name string) (Profile, error) {
+	for _, p := range Profiles(conf) {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+
+	return Profile{}, fmt.Errorf("profile %q not found, add a [%s%s] section to wamp.ini", name, ProfilePrefix, name)
+}
+
+func profileFrom(conf *util.INI, section string) Profile {
+	p := Profile{Name: strings.TrimPrefix(section, ProfilePrefix), Settings: map[string]string{}}
+	for _, k := range profileKeys {
+		if value, found := conf.GetConf(section, k.key); found && value != "" {
+			p.Settings[k.setting] = value
+		}
+	}
+
+	return p
+}
+
+// Validate checks every setting of the profile against the schema, e.g.
+// that the Apache and MySQL versions are installed.
+func (p Profile) Validate(l Layout) error {
+	for _, name := range p.names() {
+		s, err := LookupSetting(name)
+		if err != nil {
+			return err
+		}
+
+		if err := s.Validate(l, p.Settings[name]); err != nil {
+			return fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Apply writes the settings of the profile into conf and records it as the
+// active profile.
+func (p Profile) Apply(conf *util.INI) {
+	for _, name := range p.names() {
+		section, key, _ := strings.Cut(name, ".")
+		conf.SetConf(section, key, p.Settings[name])
+	}
+
+	conf.SetConf("profile", "active", p.Name)
+}
+
+// names returns the setting names of the profile, sorted.
+func (p Profile) names() []string {
+	names := make([]string, 0, len(p.Settings))
+	for name := range p.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,142 @@
+package wamp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aziyan99/wamp/internal/util"
+)
+
+// Sources a setting value can come from, lowest precedence first.
+const (
+	SourceDefault = "default"
+	SourceFile    = "wamp.ini"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Layer is the value one source has for a setting.
+type Layer struct {
+	Source string `json:"source"`
+	// Origin tells where exactly, e.g. the file path or the variable name
+	Origin string `json:"origin,omitempty"`
+	Value  string `json:"value"`
+	Set    bool   `json:"set"`
+}
+
+// Resolved is the effective value of a setting and the layers it was picked from.
+type Resolved struct {
+	Setting Setting
+	Value   string
+	Source  string
+	Origin  string
+	Layers  []Layer
+}
+
+// Resolver looks settings up in layers: the schema defaults, wamp.ini, the
+// WAMP_<SECTION>_<KEY> environment variables and the overrides given on the
+// command line. A later layer wins.
+type Resolver struct {
+	conf      *util.INI
+	confPath  string
+	overrides map[string]string
+	lookupEnv func(key string) (string, bool)
+}
+
+// NewResolver returns a resolver over conf, read from confPath, with the
+// command line overrides keyed by setting name. conf may be nil when
+// wamp.ini does not exist yet.
+func NewResolver(conf *util.INI, confPath string, overrides map[string]string) *Resolver {
+	if conf == nil {
+		conf = util.NewINI()
+	}
+
+	return &Resolver{
+		conf:      conf,
+		confPath:  confPath,
+		overrides: overrides,
+		lookupEnv: os.LookupEnv,
+	}
+}
+
+// WithConf returns a resolver with the same overrides over another wamp.ini
+// document, e.g. one that was just edited.
+func (r *Resolver) WithConf(conf *util.INI) *Resolver {
+	copied := *r
+	copied.conf = conf
+
+	return &copied
+}
+
+// EnvName returns the environment variable overriding s, e.g. WAMP_APACHE_ACTIVE.
+func EnvName(s Setting) string {
+	name := strings.ToUpper(s.Section + "_" + s.Key)
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+
+	return "WAMP_" + name
+}
+
+// Resolve returns the effective value of s.
+func (r *Resolver) Resolve(s Setting) Resolved {
+	layers := []Layer{{Source: SourceDefault, Value: s.Default, Set: s.Default != ""}}
+
+	fileLayer := Layer{Source: SourceFile, Origin: r.confPath}
+	fileLayer.Value, fileLayer.Set = r.conf.GetConf(s.Section, s.Key)
+	layers = append(layers, fileLayer)
+
+	envLayer := Layer{Source: SourceEnv, Origin: EnvName(s)}
+	envLayer.Value, envLayer.Set = r.lookupEnv(envLayer.Origin)
+	layers = append(layers, envLayer)
+
+	flagLayer := Layer{Source: SourceFlag, Origin: "--set " + s.Name()}
+	flagLayer.Value, flagLayer.Set = r.overrides[s.Name()]
+	layers = append(layers, flagLayer)
+
+	resolved := Resolved{Setting: s, Value: s.Default, Source: SourceDefault, Layers: layers}
+	for _, layer := range layers[1:] {
+		if layer.Set {
+			resolved.Value = layer.Value
+			resolved.Source = layer.Source
+			resolved.Origin = layer.Origin
+		}
+	}
+
+	return resolved
+}
+
+// Get returns the effective value of the setting called name ("section.key"),
+// or an empty string for an unknown setting.
+func (r *Resolver) Get(name string) string {
+	s, err := LookupSetting(name)
+	if err != nil {
+		return ""
+	}
+
+	return r.Resolve(s).Value
+}
+
+// ValidateOverrides checks the environment variables and command line
+// overrides that are set with Setting.Validate, so e.g. WAMP_APACHE_PORT=abc
+// is refused instead of ending up in httpd.conf. wamp.ini is checked by
+// 'wamp config set' when it is written.
+func (r *Resolver) ValidateOverrides(l Layout) error {
+	for _, s := range Settings {
+		for _, layer := range r.Resolve(s).Layers {
+			if !layer.Set || (layer.Source != SourceEnv && layer.Source != SourceFlag) {
+				continue
+			}
+
+			if err := s.Validate(l, layer.Value); err != nil {
+				return fmt.Errorf("%s: %w", layer.Origin, err)
+			}
+		}
+	}
+
+	return nil
+}